@@ -0,0 +1,195 @@
+package main
+
+import (
+	tedwards "github.com/consensys/gnark-crypto/ecc/twistededwards"
+	"github.com/consensys/gnark-crypto/hash"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/std/algebra/native/twistededwards"
+	"github.com/consensys/gnark/std/hash/mimc"
+	"github.com/consensys/gnark/std/hash/poseidon2"
+)
+
+// 叶子哈希和内部节点哈希使用不同的域分离前缀，防止把一个叶子哈希错当成某个内部节点的哈希来伪造证明
+// （RFC 6962 里同样的问题，修法也一样：给两层套上不同的标签）
+var (
+	leafDomainTag = []byte{0x00}
+	nodeDomainTag = []byte{0x01}
+)
+
+// HashSchemeID 标识树外/电路内要使用哪一种哈希原语，属于电路的编译期配置，不是见证输入
+type HashSchemeID int
+
+const (
+	HashMiMC HashSchemeID = iota
+	HashPoseidon
+	HashPedersen
+	// HashOpenZeppelin 产出的树和 OpenZeppelin StandardMerkleTree 的证明互相兼容，
+	// 只用于链下构建由 Solidity MerkleProof.verify 消费的树，没有对应的 gnark 电路实现
+	HashOpenZeppelin
+)
+
+// HashScheme 是树外构建时使用的哈希接口，叶子和内部节点分别打上不同的域分离标签
+type HashScheme interface {
+	HashLeaf(data []byte) []byte
+	HashNode(levelBytes, left, right []byte) []byte
+}
+
+// mimcScheme 是当前默认使用的 MiMC-BN254 方案
+type mimcScheme struct{}
+
+func (mimcScheme) HashLeaf(data []byte) []byte {
+	return hashFunctionMulti(leafDomainTag, data)
+}
+
+func (mimcScheme) HashNode(levelBytes, left, right []byte) []byte {
+	return hashFunctionMulti(nodeDomainTag, levelBytes, left, right)
+}
+
+// hashFunctionMulti 把每个分片当作独立的哈希输入依次喂给 MiMC，而不是先拼接成一个字节串再整体哈希：
+// MiMC_BN254 的底层实现要求每次写入要么不足一个域宽度（会左侧补零凑成一块），要么是域宽度的整数倍，
+// 域分离标签只有 1 字节，和任意长度的数据拼在一起很容易凑不成整数倍而直接 panic；
+// 分开写入和电路里 mimcHash.Write(tag, data...) 逐个变量写入是同一件事，结果也对得上
+func hashFunctionMulti(chunks ...[]byte) []byte {
+	hFunc := hash.MIMC_BN254.New()
+	for _, c := range chunks {
+		hFunc.Write(c)
+	}
+	return hFunc.Sum(nil)
+}
+
+// poseidonScheme 使用 Poseidon2-BN254，相比 MiMC 单次哈希的约束数少很多，适合 leafnum 很大的场景
+type poseidonScheme struct{}
+
+func (poseidonScheme) HashLeaf(data []byte) []byte {
+	h := hash.POSEIDON2_BN254.New()
+	h.Write(leafDomainTag)
+	h.Write(data)
+	return h.Sum(nil)
+}
+
+func (poseidonScheme) HashNode(levelBytes, left, right []byte) []byte {
+	h := hash.POSEIDON2_BN254.New()
+	h.Write(nodeDomainTag)
+	h.Write(levelBytes)
+	h.Write(left)
+	h.Write(right)
+	return h.Sum(nil)
+}
+
+// pedersenScheme 用 Pedersen 承诺做哈希：把域分离标签和内容都当作标量，分别乘上各自的基点后相加
+// 这里复用 hashFunction 把任意长度的输入先压成定长标量，再交给 Pedersen 承诺，避免自己实现变长编码
+type pedersenScheme struct{}
+
+func (pedersenScheme) HashLeaf(data []byte) []byte {
+	return pedersenCommit(leafDomainTag, data)
+}
+
+func (pedersenScheme) HashNode(levelBytes, left, right []byte) []byte {
+	return pedersenCommit(nodeDomainTag, append(append(append([]byte{}, levelBytes...), left...), right...))
+}
+
+// NewHashScheme 按 ID 返回对应的树外哈希方案
+func NewHashScheme(id HashSchemeID) HashScheme {
+	switch id {
+	case HashPoseidon:
+		return poseidonScheme{}
+	case HashPedersen:
+		return pedersenScheme{}
+	case HashOpenZeppelin:
+		return ozScheme{}
+	default:
+		return mimcScheme{}
+	}
+}
+
+// defineWithPoseidon 和 defineWithMiMC 结构完全一致，只是把哈希原语换成 gnark 内置的 Poseidon2 电路实现，
+// 它对应的正是 poseidonScheme 在树外调用的 hash.POSEIDON2_BN254，这样树外建树和电路内验证走的是同一个置换，
+// 不会出现“树外用 Poseidon 建好的树，电路内却验证不过”的问题
+func (circuit *MerkleProofCircuit) defineWithPoseidon(api frontend.API) error {
+	poseidonHash, err := poseidon2.New(api)
+	if err != nil {
+		return err
+	}
+
+	poseidonHash.Write(leafDomainTag[0])
+	for i := 0; i < len(circuit.Leaf); i++ {
+		poseidonHash.Write(circuit.Leaf[i])
+	}
+	computedHash := poseidonHash.Sum()
+	poseidonHash.Reset()
+
+	pathLen := len(circuit.Path)
+	q := frontend.Variable(0)
+	for i := 0; i < pathLen; i++ {
+		pathHash := circuit.Path[i]
+		helper := circuit.Helper[i]
+		num := circuit.LeafNum[i]
+
+		poseidonHash.Write(nodeDomainTag[0], circuit.LeafNUm_byte[i], computedHash, pathHash)
+		leftHash := poseidonHash.Sum()
+		poseidonHash.Reset()
+
+		poseidonHash.Write(nodeDomainTag[0], circuit.LeafNUm_byte[i], pathHash, computedHash)
+		rightHash := poseidonHash.Sum()
+		poseidonHash.Reset()
+
+		computedHash = api.Select(helper, rightHash, leftHash)
+		q = api.Select(helper, api.Add(q, num), q)
+	}
+
+	api.AssertIsEqual(computedHash, circuit.RootHash)
+	api.AssertIsEqual(circuit.LeafIndex, q)
+	return nil
+}
+
+// pedersenCombine 是 pedersenCommit 的电路版本：tag 和 data 分别先过一遍 MiMC 压成标量，
+// 再各自乘上 G/G2 两个生成元后相加，和树外完全相同的两步，取值才能对得上
+func pedersenCombine(api frontend.API, curve twistededwards.Curve, mimcHash *mimc.MiMC, tag frontend.Variable, data ...frontend.Variable) frontend.Variable {
+	mimcHash.Write(tag)
+	tagScalar := mimcHash.Sum()
+	mimcHash.Reset()
+
+	mimcHash.Write(data...)
+	dataScalar := mimcHash.Sum()
+	mimcHash.Reset()
+
+	base := twistededwards.Point{X: curve.Params().Base[0], Y: curve.Params().Base[1]}
+	g2 := curve.ScalarMul(base, 7)
+
+	pa := curve.ScalarMul(base, tagScalar)
+	pb := curve.ScalarMul(g2, dataScalar)
+	sum := curve.Add(pa, pb)
+	return sum.X
+}
+
+// defineWithPedersen 用 Pedersen 承诺重算 Merkle 路径，承诺用的两个生成元是曲线基点和基点的一个固定倍数，
+// 和树外的 pedersenScheme.HashLeaf/HashNode 一一对应：tag 和数据各自独立哈希成标量后再做 EC 运算
+func (circuit *MerkleProofCircuit) defineWithPedersen(api frontend.API) error {
+	curve, err := twistededwards.NewEdCurve(api, tedwards.BN254)
+	if err != nil {
+		return err
+	}
+	mimcHash, err := mimc.NewMiMC(api)
+	if err != nil {
+		return err
+	}
+
+	computedHash := pedersenCombine(api, curve, &mimcHash, leafDomainTag[0], circuit.Leaf...)
+
+	pathLen := len(circuit.Path)
+	q := frontend.Variable(0)
+	for i := 0; i < pathLen; i++ {
+		pathHash := circuit.Path[i]
+		helper := circuit.Helper[i]
+		num := circuit.LeafNum[i]
+
+		leftHash := pedersenCombine(api, curve, &mimcHash, nodeDomainTag[0], circuit.LeafNUm_byte[i], computedHash, pathHash)
+		rightHash := pedersenCombine(api, curve, &mimcHash, nodeDomainTag[0], circuit.LeafNUm_byte[i], pathHash, computedHash)
+		computedHash = api.Select(helper, rightHash, leftHash)
+		q = api.Select(helper, api.Add(q, num), q)
+	}
+
+	api.AssertIsEqual(computedHash, circuit.RootHash)
+	api.AssertIsEqual(circuit.LeafIndex, q)
+	return nil
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/backend/groth16"
+	"github.com/consensys/gnark/frontend"
+	r1cs2 "github.com/consensys/gnark/frontend/cs/r1cs"
+)
+
+// TestHashSchemesBuildTreeAndProve 对 MiMC/Poseidon/Pedersen 三种 HashScheme 各自建一棵小树、
+// 取一条证明路径，然后跑完整的 Compile -> Setup -> Prove -> Verify，确认树外 HashScheme 和
+// MerkleProofCircuit.Define 里对应的 defineWith* 分支用的是同一套哈希——runHashSchemeBenchmark
+// 只测了编译和证明耗时，从没真正调用过 groth16.Verify 确认证明是否通过
+func TestHashSchemesBuildTreeAndProve(t *testing.T) {
+	leaves := make([][]byte, 4)
+	for i := range leaves {
+		b := make([]byte, dag_size)
+		b[dag_size-1] = byte(i + 1)
+		leaves[i] = b
+	}
+	path, pathByte := calculateLeafNodesInPath(2)
+	const targetLeaf = 1
+
+	schemes := []struct {
+		name string
+		id   HashSchemeID
+	}{
+		{"MiMC", HashMiMC},
+		{"Poseidon", HashPoseidon},
+		{"Pedersen", HashPedersen},
+	}
+
+	for _, s := range schemes {
+		t.Run(s.name, func(t *testing.T) {
+			tree := MerkleTree{Leaves: append([][]byte{}, leaves...), HashID: s.id}
+			tree.BuildTree(path, pathByte)
+			root := tree.GetRoot()
+			proof := tree.GetProof(targetLeaf)
+
+			var circuit MerkleProofCircuit
+			circuit.HashID = s.id
+			circuit.Leaf = make([]frontend.Variable, len(leaves[targetLeaf])/32)
+			circuit.Path = make([]frontend.Variable, len(proof))
+			circuit.LeafNum = make([]frontend.Variable, len(proof))
+			circuit.Helper = make([]frontend.Variable, len(proof))
+			circuit.LeafNUm_byte = make([]frontend.Variable, len(proof))
+
+			r1cs, err := frontend.Compile(ecc.BN254.ScalarField(), r1cs2.NewBuilder, &circuit)
+			if err != nil {
+				t.Fatalf("compile: %v", err)
+			}
+			pk, vk, err := groth16.Setup(r1cs)
+			if err != nil {
+				t.Fatalf("setup: %v", err)
+			}
+
+			var assignment MerkleProofCircuit
+			assignment.HashID = s.id
+			assignment.RootHash = root
+			assignment.LeafIndex = targetLeaf
+			assignment.Leaf = make([]frontend.Variable, len(leaves[targetLeaf])/32)
+			for i := 0; i < len(leaves[targetLeaf]); i += 32 {
+				assignment.Leaf[i/32] = leaves[targetLeaf][i : i+32]
+			}
+			assignment.Path = make([]frontend.Variable, len(proof))
+			assignment.LeafNum = make([]frontend.Variable, len(proof))
+			assignment.Helper = make([]frontend.Variable, len(proof))
+			assignment.LeafNUm_byte = make([]frontend.Variable, len(proof))
+			for i := 0; i < len(proof); i++ {
+				assignment.Path[i] = proof[i]
+				assignment.LeafNum[i] = path[i]
+				assignment.Helper[i] = targetLeaf >> i & 1
+				assignment.LeafNUm_byte[i] = pathByte[i]
+			}
+
+			witness, err := frontend.NewWitness(&assignment, ecc.BN254.ScalarField())
+			if err != nil {
+				t.Fatalf("witness: %v", err)
+			}
+			publicWitness, err := witness.Public()
+			if err != nil {
+				t.Fatalf("public witness: %v", err)
+			}
+
+			proofResult, err := groth16.Prove(r1cs, pk, witness)
+			if err != nil {
+				t.Fatalf("prove: %v", err)
+			}
+			if err := groth16.Verify(proofResult, vk, publicWitness); err != nil {
+				t.Fatalf("verify: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"bytes"
+
+	"golang.org/x/crypto/sha3"
+)
+
+// keccak256 是 Solidity/OpenZeppelin 生态统一使用的哈希函数
+func keccak256(data ...[]byte) []byte {
+	h := sha3.NewLegacyKeccak256()
+	for _, d := range data {
+		h.Write(d)
+	}
+	return h.Sum(nil)
+}
+
+// LeafEncoder 把原始字段编码成 OpenZeppelin `StandardMerkleTree` 认可的叶子字节串：
+// keccak256(keccak256(abi.encode(values...))) ，这里用简单拼接代替完整的 ABI 编码，
+// 调用方传入的每个字段都应当已经是定长 32 字节，和 Solidity 里的 uint256/bytes32 保持一致
+func LeafEncoder(fields ...[]byte) []byte {
+	var encoded []byte
+	for _, f := range fields {
+		encoded = append(encoded, f...)
+	}
+	return keccak256(keccak256(encoded))
+}
+
+// ozScheme 复刻 OpenZeppelin StandardMerkleTree 的习惯：叶子先做一次 LeafEncoder 风格的双重哈希
+// （这里假设 HashLeaf 收到的 data 已经是编码后的字段拼接，直接做双重 keccak），
+// 内部节点按哈希值排序后拼接再 keccak，使得同一棵树上生成的证明能直接喂给 OpenZeppelin 的
+// `MerkleProof.verify`，不需要关心子节点在树里原本是左还是右
+type ozScheme struct{}
+
+func (ozScheme) HashLeaf(data []byte) []byte {
+	return keccak256(keccak256(data))
+}
+
+func (ozScheme) HashNode(levelBytes, left, right []byte) []byte {
+	if bytes.Compare(left, right) <= 0 {
+		return keccak256(left, right)
+	}
+	return keccak256(right, left)
+}
@@ -18,6 +18,18 @@ import (
 	"time"
 )
 
+// 下面几个常量驱动 main() 里的单叶子/多叶子/哈希方案 demo：
+// leafnum 是示例树的叶子数量，必须是 2 的幂，和 calculateLeafNodesInPath 按 log2(leafnum) 算出的深度配合；
+// dag_size 是每个叶子填充到的字节数，对齐 BN254 标量域宽度（32 字节），circuit.Leaf 按 32 字节一段切分就是这么来的；
+// leafIndex 是单叶子证明 demo（main 的前半段和 runHashSchemeBenchmark）固定验证的那个叶子下标；
+// chalnum 是 runMultiProofDemo 里打包进一个 MerkleMultiProofCircuit 一起验证的叶子个数
+const (
+	leafnum   = 8
+	dag_size  = 32
+	leafIndex = 1
+	chalnum   = 3
+)
+
 type MerkleProofCircuit struct {
 	//LeafHash frontend.Variable `gnark:",public"` // 公开：待验证的叶子节点,由修改者发送
 	//Leaf         []frontend.Variable
@@ -28,63 +40,61 @@ type MerkleProofCircuit struct {
 	Helper       []frontend.Variable // 私有：路径中的辅助值，用来确定哈希方向（左右）
 	LeafIndex    frontend.Variable   //当前叶子结点的索引值
 	LeafNUm_byte []frontend.Variable // 私有：Merkle 路径的字节表示
+	HashID       HashSchemeID        // 编译期配置，不是见证输入：选择 MiMC/Poseidon/Pedersen 中的哪一种
 }
 
 func (circuit *MerkleProofCircuit) Define(api frontend.API) error {
+	switch circuit.HashID {
+	case HashPoseidon:
+		return circuit.defineWithPoseidon(api)
+	case HashPedersen:
+		return circuit.defineWithPedersen(api)
+	default:
+		return circuit.defineWithMiMC(api)
+	}
+}
+
+// defineWithMiMC 是原来的实现，叶子和内部节点分别打上 leafDomainTag / nodeDomainTag 前缀，
+// 避免一个叶子的哈希被当成某个内部节点的哈希重用（第二原像攻击）
+func (circuit *MerkleProofCircuit) defineWithMiMC(api frontend.API) error {
 	// 初始化 MiMC 哈希函数
 	mimcHash, _ := mimc.NewMiMC(api)
-	//mimc, _ := mimc.NewMiMC(api)
 
-	// 开始验证 Merkle 路径
-	computedLeaf := circuit.Leaf
-	for i := 0; i < len(computedLeaf); i++ {
-		mimcHash.Write(computedLeaf[i])
+	// 开始验证 Merkle 路径，叶子哈希加上域分离标签
+	mimcHash.Write(leafDomainTag[0])
+	for i := 0; i < len(circuit.Leaf); i++ {
+		mimcHash.Write(circuit.Leaf[i])
 	}
-	//mimcHash.Write(computedLeaf)
 	computedHash := mimcHash.Sum()
 	mimcHash.Reset()
 	pathLen := len(circuit.Path)
 	q := frontend.Variable(0)
-	z := frontend.Variable(1) //当前计算节点的叶子结点数
-	//api.Println("leafindex:", circuit.LeafIndex)
 
 	for i := 0; i < pathLen; i++ {
 		// 依次哈希路径中的每个节点
 		pathHash := circuit.Path[i]
 		helper := circuit.Helper[i]
 		num := circuit.LeafNum[i]
-		z = api.Add(z, num)
 
 		// 如果 helper 为 1，表示 computedHash 是右节点，pathHash 是左节点
 		// 如果 helper 为 0，表示 computedHash 是左节点，pathHash 是右节点
-		// 创建两个哈希器，分别用于不同顺序的哈希计算
-		// 当 helper 为 0 时，我们将 computedHash 作为左侧，pathHash 作为右侧
-		mimcHash.Write(circuit.LeafNUm_byte[i], computedHash, pathHash)
-		//mimcHash.Write(computedHash, pathHash)
+		// 内部节点哈希加上域分离标签，再拼上每层自己的 LeafNUm_byte
+		mimcHash.Write(nodeDomainTag[0], circuit.LeafNUm_byte[i], computedHash, pathHash)
 		leftHash := mimcHash.Sum()
 		mimcHash.Reset()
 
-		// 当 helper 为 1 时，pathHash 作为左侧，computedHash 作为右侧
-		mimcHash.Write(circuit.LeafNUm_byte[i], pathHash, computedHash)
-		//mimcHash.Write(pathHash, computedHash)
+		mimcHash.Write(nodeDomainTag[0], circuit.LeafNUm_byte[i], pathHash, computedHash)
 		rightHash := mimcHash.Sum()
 		mimcHash.Reset()
 
 		// 使用 api.Select 来选择哪个哈希应该用于计算
 		computedHash = api.Select(helper, rightHash, leftHash)
 		q = api.Select(helper, api.Add(q, num), q)
-		//api.Println("helper", helper)
-		//api.Println("q", q)
-		//computedHash = api.Select(helper, mimcHash.Hash(api, pathHash, computedHash), mimcHash.Hash(api, computedHash, pathHash))
 	}
 
 	// 最后，computedHash 应该等于 root
 	api.AssertIsEqual(computedHash, circuit.RootHash)
-	//api.Println("compute roothash:", computedHash)
-	//api.Println(" roothash:", circuit.RootHash)
 	api.AssertIsEqual(circuit.LeafIndex, q)
-	//api.Println("q:", q)
-	//api.Println(" index:", circuit.LeafIndex)
 
 	return nil
 }
@@ -101,10 +111,13 @@ func hashFunction(data []byte) []byte {
 type MerkleTree struct {
 	Leaves     [][]byte   // 原始叶子节点数据
 	TreeLayers [][][]byte // Merkle 树的所有层，包括根节点
+	HashID     HashSchemeID
 }
 
 // 构建 Merkle 树
 func (m *MerkleTree) BuildTree(path []int, path_byte [][]byte) {
+	scheme := NewHashScheme(m.HashID)
+
 	// 如果叶子节点数量是奇数，复制最后一个叶子节点以使数量为偶数
 	if len(m.Leaves)%2 != 0 {
 		m.Leaves = append(m.Leaves, m.Leaves[len(m.Leaves)-1])
@@ -114,7 +127,7 @@ func (m *MerkleTree) BuildTree(path []int, path_byte [][]byte) {
 	hashedLeaves := make([][]byte, len(m.Leaves))
 	for i, leaf := range m.Leaves {
 
-		hashedLeaves[i] = hashFunction(leaf)
+		hashedLeaves[i] = scheme.HashLeaf(leaf)
 	}
 
 	// 将叶子节点层添加到树层次中
@@ -126,9 +139,7 @@ func (m *MerkleTree) BuildTree(path []int, path_byte [][]byte) {
 	for len(currentLevel) > 1 {
 		var newLevel [][]byte
 		for i := 0; i < len(currentLevel); i += 2 {
-			combined := append(append(path_byte[index], currentLevel[i]...), currentLevel[i+1]...)
-			//combined := append(currentLevel[i], currentLevel[i+1]...)
-			combinedHash := hashFunction(combined)
+			combinedHash := scheme.HashNode(path_byte[index], currentLevel[i], currentLevel[i+1])
 			newLevel = append(newLevel, combinedHash)
 		}
 
@@ -316,100 +327,203 @@ func main() {
 	//if err != nil {
 	//	panic(err)
 	//}
-	publicWitnessgroup := make([]witness2.Witness, chalnum)
-	for i := 0; i < chalnum; i++ {
-		publicWitnessgroup[i] = publicWitness
-	}
-
-	var proofs []groth16.Proof
 
 	t_zkProof := time.Now()
-	//zkproof, err := groth16.Prove(r1cs, pk, witness)
-	//批量生成证明，这里只用一条路径代替
-	for i := 0; i < chalnum; i++ {
-		zkproof, err := groth16.Prove(r1cs, pk, witness)
-		proofs = append(proofs, zkproof)
-		if err != nil {
-			fmt.Printf("Prove failed： %v\n", err)
-			return
-		}
-	}
+	zkproof, err := groth16.Prove(r1cs, pk, witness)
 	t_zkProof_end := time.Now()
-	//if err != nil {
-	//	fmt.Printf("Prove failed： %v\n", err)
-	//	return
-	//}
+	if err != nil {
+		fmt.Printf("Prove failed： %v\n", err)
+		return
+	}
 	log.Println("prove time: ", t_zkProof_end.Sub(t_zkProof))
 
-	//publicWitness, err := witness.Public()
-	////fmt.Println(err)
-	//if err != nil {
-	//	fmt.Println("public witness:", err)
-	//}
-
 	t_zkVerify := time.Now()
-	// 使用goroutines并行验证
-	var wg sync.WaitGroup
-	resultChan := make(chan bool, len(proofs)) // 用于收集验证结果
-	verified := true
-	wg.Add(len(proofs))
-	for i, proof := range proofs {
-		go verifyProof(&wg, proof, vk, publicWitnessgroup[i], resultChan)
+	if err := groth16.Verify(zkproof, vk, publicWitness); err != nil {
+		fmt.Println("verify:", err)
+	} else {
+		fmt.Println("单叶子证明验证通过")
+	}
+	t_zkVerify_end := time.Now()
+	log.Println("verify time: ", t_zkVerify_end.Sub(t_zkVerify))
+
+	// 以下是 chalnum 个不同叶子的批量证明：不再对同一条路径重复跑 chalnum 遍 MerkleProofCircuit，
+	// 而是把 chalnum 个索引的认证路径压缩进一个 MerkleMultiProofCircuit，一次 Groth16 证明覆盖全部
+	runMultiProofDemo(&merkleTree, merkleRoot, leaves, path_byte)
+
+	// 对比三种哈希原语在相同 leafnum 下的 R1CS 约束数和证明耗时
+	runHashSchemeBenchmark(leaves, path, path_byte)
+}
+
+// runHashSchemeBenchmark 用同一批叶子分别编译 MiMC / Poseidon / Pedersen 三种 MerkleProofCircuit，
+// 对比 R1CS 约束数量和单次证明耗时，方便评估换哈希原语的收益
+func runHashSchemeBenchmark(leaves [][]byte, path []int, path_byte [][]byte) {
+	schemes := []struct {
+		name string
+		id   HashSchemeID
+	}{
+		{"MiMC", HashMiMC},
+		{"Poseidon", HashPoseidon},
+		{"Pedersen", HashPedersen},
 	}
 
-	// 等待所有goroutines完成
-	wg.Wait()
-	close(resultChan)
-	// 检查所有验证是否通过
-	for result := range resultChan {
-		if !result {
-			verified = false
-			break
+	for _, s := range schemes {
+		tree := MerkleTree{Leaves: append([][]byte{}, leaves...), HashID: s.id}
+		tree.BuildTree(path, path_byte)
+		root := tree.GetRoot()
+		proof := tree.GetProof(leafIndex)
+
+		var circuit MerkleProofCircuit
+		circuit.HashID = s.id
+		circuit.Leaf = make([]frontend.Variable, len(leaves[leafIndex])/32)
+		circuit.Path = make([]frontend.Variable, len(proof))
+		circuit.LeafNum = make([]frontend.Variable, len(proof))
+		circuit.Helper = make([]frontend.Variable, len(proof))
+		circuit.LeafNUm_byte = make([]frontend.Variable, len(proof))
+
+		t_compile := time.Now()
+		r1cs, err := frontend.Compile(ecc.BN254.ScalarField(), r1cs2.NewBuilder, &circuit)
+		t_compile_end := time.Now()
+		if err != nil {
+			fmt.Printf("[%s] compile failed: %v\n", s.name, err)
+			continue
+		}
+		fmt.Printf("[%s] constraints: %d, compile time: %v\n", s.name, r1cs.GetNbConstraints(), t_compile_end.Sub(t_compile))
+
+		pk, _, err := groth16.Setup(r1cs)
+		if err != nil {
+			fmt.Printf("[%s] setup failed: %v\n", s.name, err)
+			continue
+		}
+
+		var assignment MerkleProofCircuit
+		assignment.HashID = s.id
+		assignment.RootHash = root
+		assignment.LeafIndex = leafIndex
+		assignment.Leaf = make([]frontend.Variable, len(leaves[leafIndex])/32)
+		for i := 0; i < len(leaves[leafIndex]); i += 32 {
+			assignment.Leaf[i/32] = leaves[leafIndex][i : i+32]
 		}
+		assignment.Path = make([]frontend.Variable, len(proof))
+		assignment.LeafNum = make([]frontend.Variable, len(proof))
+		assignment.Helper = make([]frontend.Variable, len(proof))
+		assignment.LeafNUm_byte = make([]frontend.Variable, len(proof))
+		for i := 0; i < len(proof); i++ {
+			assignment.Path[i] = proof[i]
+			assignment.LeafNum[i] = path[i]
+			assignment.Helper[i] = leafIndex >> i & 1
+			assignment.LeafNUm_byte[i] = path_byte[i]
+		}
+
+		witness, err := frontend.NewWitness(&assignment, ecc.BN254.ScalarField())
+		if err != nil {
+			fmt.Printf("[%s] witness failed: %v\n", s.name, err)
+			continue
+		}
+
+		t_prove := time.Now()
+		_, err = groth16.Prove(r1cs, pk, witness)
+		t_prove_end := time.Now()
+		if err != nil {
+			fmt.Printf("[%s] prove failed: %v\n", s.name, err)
+			continue
+		}
+		fmt.Printf("[%s] prove time: %v\n", s.name, t_prove_end.Sub(t_prove))
 	}
-	if verified {
-		fmt.Println("所有证明验证通过")
-	} else {
-		fmt.Println("有证明验证失败")
+}
+
+// runMultiProofDemo 演示批量多叶子证明：把 chalnum 个叶子索引打包进一个 MerkleMultiProofCircuit
+func runMultiProofDemo(merkleTree *MerkleTree, merkleRoot []byte, leaves [][]byte, path_byte [][]byte) {
+	depth := len(path_byte)
+	indices := make([]int, chalnum)
+	for i := 0; i < chalnum; i++ {
+		indices[i] = i % len(merkleTree.Leaves)
 	}
-	t_zkVerify_end := time.Now()
 
-	// 导出 Verification Key
-	//vkBytes, err := json.Marshal(vk)
-	//if err != nil {
-	//	panic(err)
-	//}
-	//err = ioutil.WriteFile("vk.json", vkBytes, 0644)
-	//if err != nil {
-	//	panic(err)
-	//}
-	//
-	//// 导出 Proof
-	//proofBytes, err := json.Marshal(proof)
-	//if err != nil {
-	//	panic(err)
-	//}
-	//err = ioutil.WriteFile("proof.json", proofBytes, 0644)
-	//if err != nil {
-	//	panic(err)
-	//}
+	leafIndex, parentIndex, siblings, hasSibling, helperBits := merkleTree.BuildMultiProofWitness(indices)
+	fmt.Printf("multiproof: %d leaves, %d compressed siblings (vs %d per-leaf siblings uncompressed)\n",
+		len(indices), len(siblings), len(indices)*depth)
+
+	var multiCircuit, multiAssignment MerkleMultiProofCircuit
+	multiCircuit.Leaves = make([]frontend.Variable, chalnum)
+	multiCircuit.LeafIndex = make([]frontend.Variable, chalnum)
+	multiCircuit.ParentIndex = make([][]frontend.Variable, chalnum)
+	multiCircuit.HasSibling = make([][]frontend.Variable, chalnum)
+	multiCircuit.HelperBits = make([][]frontend.Variable, chalnum)
+	multiCircuit.Siblings = make([]frontend.Variable, len(siblings))
+	multiCircuit.LeafNUm_byte = make([]frontend.Variable, depth)
+	for i := 0; i < chalnum; i++ {
+		multiCircuit.ParentIndex[i] = make([]frontend.Variable, depth)
+		multiCircuit.HasSibling[i] = make([]frontend.Variable, depth)
+		multiCircuit.HelperBits[i] = make([]frontend.Variable, depth)
+	}
 
-	//// 导出 Public Input
-	//publicWitness, err := witness.Public()
-	//if err != nil {
-	//	panic(err)
-	//}
+	multiR1cs, err := frontend.Compile(ecc.BN254.ScalarField(), r1cs2.NewBuilder, &multiCircuit)
+	if err != nil {
+		fmt.Printf("multiproof compile failed: %v\n", err)
+		return
+	}
+	multiPk, multiVk, err := groth16.Setup(multiR1cs)
+	if err != nil {
+		fmt.Printf("multiproof setup failed: %v\n", err)
+		return
+	}
 
-	//t_zkVerify := time.Now()
-	//err = groth16.Verify(zkproof, vk, publicWitness)
-	//t_zkVerify_end := time.Now()
-	//if err != nil {
-	//	fmt.Println("verify:", err)
-	//}
-	log.Println("verify time: ", t_zkVerify_end.Sub(t_zkVerify))
+	multiAssignment.Leaves = make([]frontend.Variable, chalnum)
+	multiAssignment.LeafIndex = make([]frontend.Variable, chalnum)
+	multiAssignment.ParentIndex = make([][]frontend.Variable, chalnum)
+	multiAssignment.HasSibling = make([][]frontend.Variable, chalnum)
+	multiAssignment.HelperBits = make([][]frontend.Variable, chalnum)
+	multiAssignment.Siblings = make([]frontend.Variable, len(siblings))
+	multiAssignment.LeafNUm_byte = make([]frontend.Variable, depth)
+	multiAssignment.RootHash = merkleRoot
+	for i := 0; i < chalnum; i++ {
+		multiAssignment.Leaves[i] = leaves[leafIndex[i]]
+		multiAssignment.LeafIndex[i] = leafIndex[i]
+		multiAssignment.ParentIndex[i] = make([]frontend.Variable, depth)
+		multiAssignment.HasSibling[i] = make([]frontend.Variable, depth)
+		multiAssignment.HelperBits[i] = make([]frontend.Variable, depth)
+		for level := 0; level < depth; level++ {
+			multiAssignment.ParentIndex[i][level] = parentIndex[i][level]
+			multiAssignment.HasSibling[i][level] = hasSibling[i][level]
+			multiAssignment.HelperBits[i][level] = helperBits[i][level]
+		}
+	}
+	for i, s := range siblings {
+		multiAssignment.Siblings[i] = s
+	}
+	for level := 0; level < depth; level++ {
+		multiAssignment.LeafNUm_byte[level] = path_byte[level]
+	}
+
+	multiWitness, err := frontend.NewWitness(&multiAssignment, ecc.BN254.ScalarField())
+	if err != nil {
+		fmt.Println("multiproof witness:", err)
+		return
+	}
+	multiPublicWitness, err := multiWitness.Public()
+	if err != nil {
+		fmt.Println("multiproof public witness:", err)
+		return
+	}
 
-	//assert.ProverSucceeded(&mtCircuit, &witness, test.WithCurves(ecc.BN254))
+	t_multiProof := time.Now()
+	multiProof, err := groth16.Prove(multiR1cs, multiPk, multiWitness)
+	t_multiProof_end := time.Now()
+	if err != nil {
+		fmt.Printf("multiproof prove failed: %v\n", err)
+		return
+	}
+	log.Println("multiproof prove time: ", t_multiProof_end.Sub(t_multiProof))
 
+	t_multiVerify := time.Now()
+	err = groth16.Verify(multiProof, multiVk, multiPublicWitness)
+	t_multiVerify_end := time.Now()
+	if err != nil {
+		fmt.Println("multiproof verify failed:", err)
+	} else {
+		fmt.Println("批量多叶子证明验证通过")
+	}
+	log.Println("multiproof verify time: ", t_multiVerify_end.Sub(t_multiVerify))
 }
 
 // 计算路径中每个节点可到达的叶子结点个数
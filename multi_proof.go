@@ -0,0 +1,196 @@
+package main
+
+import (
+	"sort"
+
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/std/hash/mimc"
+)
+
+// BuildMultiProof 为一组叶子索引构造压缩多重证明
+// 思路：逐层维护当前还需要向上合并的索引集合，如果某个索引的兄弟也在集合里，
+// 说明这一对的两个孩子都已知，合并时不需要额外提供兄弟哈希；否则才从树里取出
+// 对应层的兄弟哈希放进压缩流。helperBits 按 (索引, 层) 顺序记录每个叶子在每一层是左还是右孩子
+func (m *MerkleTree) BuildMultiProof(indices []int) (leaves [][]byte, siblings [][]byte, helperBits []uint8) {
+	sorted := append([]int{}, indices...)
+	sort.Ints(sorted)
+
+	leaves = make([][]byte, len(sorted))
+	for i, idx := range sorted {
+		leaves[i] = m.Leaves[idx]
+	}
+
+	helperBits = make([]uint8, len(sorted)*(len(m.TreeLayers)-1))
+	current := append([]int{}, sorted...)
+
+	for level := 0; level < len(m.TreeLayers)-1; level++ {
+		present := make(map[int]bool, len(current))
+		for _, idx := range current {
+			present[idx] = true
+		}
+
+		seen := make(map[int]bool)
+		var next []int
+		for i, idx := range current {
+			helperBits[i*(len(m.TreeLayers)-1)+level] = uint8(idx % 2)
+
+			siblingIdx := idx ^ 1
+			if !present[siblingIdx] && siblingIdx < len(m.TreeLayers[level]) {
+				siblings = append(siblings, m.TreeLayers[level][siblingIdx])
+			}
+
+			parent := idx / 2
+			if !seen[parent] {
+				seen[parent] = true
+				next = append(next, parent)
+			}
+		}
+		current = next
+	}
+
+	return leaves, siblings, helperBits
+}
+
+// BuildMultiProofWitness 把 BuildMultiProof 同一套压缩逻辑展开成 MerkleMultiProofCircuit 需要的形状。
+// Siblings 只在某个槽位确实没有同层搭档（present[siblingIdx] 为假）时才追加一个元素，长度就是
+// BuildMultiProof 返回的真实压缩长度，而不是按 K*depth 预留、用零值占位——没有共享兄弟的那部分电路内
+// 靠 ParentIndex 比对、压根不去消费 Siblings 流。leafIndex 原样带出排序后的叶子索引，供电路把
+// ParentIndex/HelperBits 这条链条绑回每个叶子自己声明的索引，而不能凭空伪造同父关系
+func (m *MerkleTree) BuildMultiProofWitness(indices []int) (leafIndex []int, parentIndex [][]int, siblings [][]byte, hasSibling [][]uint8, helperBits [][]uint8) {
+	sorted := append([]int{}, indices...)
+	sort.Ints(sorted)
+
+	depth := len(m.TreeLayers) - 1
+	k := len(sorted)
+	leafIndex = append([]int{}, sorted...)
+	parentIndex = make([][]int, k)
+	hasSibling = make([][]uint8, k)
+	helperBits = make([][]uint8, k)
+	for i := range sorted {
+		parentIndex[i] = make([]int, depth)
+		hasSibling[i] = make([]uint8, depth)
+		helperBits[i] = make([]uint8, depth)
+	}
+
+	current := append([]int{}, sorted...)
+	for level := 0; level < depth; level++ {
+		present := make(map[int]bool, len(current))
+		for _, idx := range current {
+			present[idx] = true
+		}
+
+		for i, idx := range current {
+			helperBits[i][level] = uint8(idx % 2)
+			parentIndex[i][level] = idx / 2
+
+			siblingIdx := idx ^ 1
+			if present[siblingIdx] {
+				hasSibling[i][level] = 0
+			} else {
+				siblings = append(siblings, m.TreeLayers[level][siblingIdx])
+				hasSibling[i][level] = 1
+			}
+		}
+		next := make([]int, len(current))
+		for i, idx := range current {
+			next[i] = idx / 2
+		}
+		current = next
+	}
+
+	return leafIndex, parentIndex, siblings, hasSibling, helperBits
+}
+
+// MerkleMultiProofCircuit 在一个 Groth16 证明里同时验证 K 个不同索引的叶子
+// 而不是像 main 里那样对同一个 MerkleProofCircuit 跑 chalnum 遍
+type MerkleMultiProofCircuit struct {
+	Leaves       []frontend.Variable   // 私有：K 个叶子原始值
+	LeafIndex    []frontend.Variable   // 私有：每个叶子自己声明的索引，ParentIndex/HelperBits 这条链条要逐层绑回这个值，不能凭空伪造同父关系
+	ParentIndex  [][]frontend.Variable // 私有：[K][depth]，每个叶子在每一层对应的父节点索引，用来判断两个槽位是否同父，并受 LeafIndex 约束
+	Siblings     []frontend.Variable   // 私有：真正压缩后的兄弟哈希流，长度等于 BuildMultiProofWitness 算出的压缩长度
+	HasSibling   [][]frontend.Variable // 私有：[K][depth]，该槽位这一层是否需要从 Siblings 流里取值（1 取值，0 说明由同层另一槽位提供）
+	HelperBits   [][]frontend.Variable // 私有：[K][depth]，每个叶子在每一层是左孩子还是右孩子
+	LeafNUm_byte []frontend.Variable   // 私有：每层的域分离标签，和 MerkleProofCircuit 保持一致
+	RootHash     frontend.Variable     `gnark:",public"`
+}
+
+// Define 按层推进 K 条路径：同一层里若两个叶子共享同一个父节点，直接用彼此的哈希配对，
+// 否则各自从压缩后的 Siblings 流里按 HasSibling 标记取下一个可用的兄弟哈希。
+// 在推进哈希之前先用 ParentIndex[i][level]*2+HelperBits[i][level] == 上一层索引 这条链条，
+// 把每个叶子的 ParentIndex 逐层绑回它自己声明的 LeafIndex，并要求最终收敛到根索引 0——
+// 否则 ParentIndex 可以被填成任意值，让不共享祖先的叶子也被电路当成"同父"接受
+func (circuit *MerkleMultiProofCircuit) Define(api frontend.API) error {
+	mimcHash, _ := mimc.NewMiMC(api)
+
+	k := len(circuit.Leaves)
+	depth := len(circuit.LeafNUm_byte)
+	streamLen := len(circuit.Siblings)
+
+	current := make([]frontend.Variable, k)
+	copy(current, circuit.LeafIndex)
+	for level := 0; level < depth; level++ {
+		for i := 0; i < k; i++ {
+			api.AssertIsEqual(current[i], api.Add(api.Mul(circuit.ParentIndex[i][level], 2), circuit.HelperBits[i][level]))
+		}
+		for i := 0; i < k; i++ {
+			current[i] = circuit.ParentIndex[i][level]
+		}
+	}
+	for i := 0; i < k; i++ {
+		api.AssertIsEqual(current[i], 0)
+	}
+
+	computed := make([]frontend.Variable, k)
+	for i := 0; i < k; i++ {
+		mimcHash.Write(leafDomainTag[0], circuit.Leaves[i])
+		computed[i] = mimcHash.Sum()
+		mimcHash.Reset()
+	}
+
+	streamPos := frontend.Variable(0)
+	for level := 0; level < depth; level++ {
+		tag := circuit.LeafNUm_byte[level]
+		next := make([]frontend.Variable, k)
+
+		for i := 0; i < k; i++ {
+			helper := circuit.HelperBits[i][level]
+
+			// 在同一层里找另一个与自己同父的槽位，用它当前这一层的哈希作为兄弟
+			pairedHash := frontend.Variable(0)
+			for j := 0; j < k; j++ {
+				if j == i {
+					continue
+				}
+				sameParent := api.IsZero(api.Sub(circuit.ParentIndex[i][level], circuit.ParentIndex[j][level]))
+				pairedHash = api.Select(sameParent, computed[j], pairedHash)
+			}
+
+			// Siblings 流已经压缩到真实长度，槽位与 (叶子,层) 不再一一对应，只能用等值扫描
+			// 做"变量下标"的数组访问；streamPos 只在 hasSibling 为 1 时才前进到下一个真实兄弟
+			hasSibling := circuit.HasSibling[i][level]
+			streamVal := frontend.Variable(0)
+			for pos := 0; pos < streamLen; pos++ {
+				atPos := api.IsZero(api.Sub(streamPos, pos))
+				streamVal = api.Select(atPos, circuit.Siblings[pos], streamVal)
+			}
+			sibling := api.Select(hasSibling, streamVal, pairedHash)
+			streamPos = api.Add(streamPos, hasSibling)
+
+			mimcHash.Write(nodeDomainTag[0], tag, computed[i], sibling)
+			leftParent := mimcHash.Sum()
+			mimcHash.Reset()
+
+			mimcHash.Write(nodeDomainTag[0], tag, sibling, computed[i])
+			rightParent := mimcHash.Sum()
+			mimcHash.Reset()
+
+			next[i] = api.Select(helper, rightParent, leftParent)
+		}
+		computed = next
+	}
+
+	for i := 0; i < k; i++ {
+		api.AssertIsEqual(computed[i], circuit.RootHash)
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/backend/groth16"
+	"github.com/consensys/gnark/frontend"
+	r1cs2 "github.com/consensys/gnark/frontend/cs/r1cs"
+)
+
+// TestMerkleMultiProofCircuitProvesRealBatch 对 8 叶子树里 3 个不同索引跑一次完整的
+// Compile -> Setup -> Prove -> Verify，确认 BuildMultiProofWitness 压缩出来的 Siblings/HasSibling/
+// ParentIndex 流真的能在电路里展开回同一个根，而不只是 runMultiProofDemo 跑过一次就算数
+func TestMerkleMultiProofCircuitProvesRealBatch(t *testing.T) {
+	leaves := make([][]byte, 8)
+	for i := range leaves {
+		b := make([]byte, dag_size)
+		b[dag_size-1] = byte(i + 1)
+		leaves[i] = b
+	}
+	path, pathByte := calculateLeafNodesInPath(3)
+
+	tree := MerkleTree{Leaves: leaves}
+	tree.BuildTree(path, pathByte)
+	root := tree.GetRoot()
+
+	indices := []int{1, 3, 6}
+	depth := len(pathByte)
+	leafIndex, parentIndex, siblings, hasSibling, helperBits := tree.BuildMultiProofWitness(indices)
+
+	var circuit MerkleMultiProofCircuit
+	circuit.Leaves = make([]frontend.Variable, len(indices))
+	circuit.LeafIndex = make([]frontend.Variable, len(indices))
+	circuit.ParentIndex = make([][]frontend.Variable, len(indices))
+	circuit.HasSibling = make([][]frontend.Variable, len(indices))
+	circuit.HelperBits = make([][]frontend.Variable, len(indices))
+	circuit.Siblings = make([]frontend.Variable, len(siblings))
+	circuit.LeafNUm_byte = make([]frontend.Variable, depth)
+	for i := range indices {
+		circuit.ParentIndex[i] = make([]frontend.Variable, depth)
+		circuit.HasSibling[i] = make([]frontend.Variable, depth)
+		circuit.HelperBits[i] = make([]frontend.Variable, depth)
+	}
+
+	r1cs, err := frontend.Compile(ecc.BN254.ScalarField(), r1cs2.NewBuilder, &circuit)
+	if err != nil {
+		t.Fatalf("compile: %v", err)
+	}
+	pk, vk, err := groth16.Setup(r1cs)
+	if err != nil {
+		t.Fatalf("setup: %v", err)
+	}
+
+	var assignment MerkleMultiProofCircuit
+	assignment.Leaves = make([]frontend.Variable, len(indices))
+	assignment.LeafIndex = make([]frontend.Variable, len(indices))
+	assignment.ParentIndex = make([][]frontend.Variable, len(indices))
+	assignment.HasSibling = make([][]frontend.Variable, len(indices))
+	assignment.HelperBits = make([][]frontend.Variable, len(indices))
+	assignment.Siblings = make([]frontend.Variable, len(siblings))
+	assignment.LeafNUm_byte = make([]frontend.Variable, depth)
+	assignment.RootHash = root
+	for i := range indices {
+		assignment.Leaves[i] = leaves[leafIndex[i]]
+		assignment.LeafIndex[i] = leafIndex[i]
+		assignment.ParentIndex[i] = make([]frontend.Variable, depth)
+		assignment.HasSibling[i] = make([]frontend.Variable, depth)
+		assignment.HelperBits[i] = make([]frontend.Variable, depth)
+		for level := 0; level < depth; level++ {
+			assignment.ParentIndex[i][level] = parentIndex[i][level]
+			assignment.HasSibling[i][level] = hasSibling[i][level]
+			assignment.HelperBits[i][level] = helperBits[i][level]
+		}
+	}
+	for i, s := range siblings {
+		assignment.Siblings[i] = s
+	}
+	for level := 0; level < depth; level++ {
+		assignment.LeafNUm_byte[level] = pathByte[level]
+	}
+
+	witness, err := frontend.NewWitness(&assignment, ecc.BN254.ScalarField())
+	if err != nil {
+		t.Fatalf("witness: %v", err)
+	}
+	publicWitness, err := witness.Public()
+	if err != nil {
+		t.Fatalf("public witness: %v", err)
+	}
+
+	proof, err := groth16.Prove(r1cs, pk, witness)
+	if err != nil {
+		t.Fatalf("prove: %v", err)
+	}
+	if err := groth16.Verify(proof, vk, publicWitness); err != nil {
+		t.Fatalf("verify: %v", err)
+	}
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"math/big"
+
+	tedwards "github.com/consensys/gnark-crypto/ecc/bn254/twistededwards"
+)
+
+// pedersenCommit 是树外（非电路）版本的 Pedersen 承诺：H(tag, data) = tag*G + scalar(data)*G2
+// G 是曲线基点，G2 是基点的一个固定倍数，充当第二个无关生成元，足够本模块里域分离两路输入用
+// 真正严谨的部署应当用可验证不知道彼此离散对数关系的生成元（nothing-up-my-sleeve），这里先用固定常数倍数占位
+func pedersenCommit(tag, data []byte) []byte {
+	curve := tedwards.GetEdwardsCurve()
+
+	var tagScalar, dataScalar big.Int
+	tagScalar.SetBytes(hashFunction(tag))
+	dataScalar.SetBytes(hashFunction(data))
+
+	var g2 tedwards.PointAffine
+	g2.ScalarMultiplication(&curve.Base, big.NewInt(7))
+
+	var p1, p2, sum tedwards.PointAffine
+	p1.ScalarMultiplication(&curve.Base, &tagScalar)
+	p2.ScalarMultiplication(&g2, &dataScalar)
+	sum.Add(&p1, &p2)
+
+	xBytes := sum.X.Bytes()
+	return xBytes[:]
+}
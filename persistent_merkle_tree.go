@@ -0,0 +1,179 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+)
+
+// persistedNode 是写进 Storage 的一个节点：叶子节点只带原始数据，内部节点带两个孩子的哈希和左子树跨了多少个叶子，
+// 后者是为了 GetProof 能只凭哈希往下走就知道该往左还是往右，而不需要把整棵树都摊在内存里
+type persistedNode struct {
+	isLeaf    bool
+	leaf      []byte
+	left      []byte
+	right     []byte
+	leftCount int
+}
+
+func encodePersistedNode(n *persistedNode) []byte {
+	buf := new(bytes.Buffer)
+	if n.isLeaf {
+		buf.WriteByte(0x00)
+		buf.Write(n.leaf)
+		return buf.Bytes()
+	}
+	buf.WriteByte(0x01)
+	binary.Write(buf, binary.BigEndian, int64(n.leftCount))
+	binary.Write(buf, binary.BigEndian, int32(len(n.left)))
+	buf.Write(n.left)
+	buf.Write(n.right)
+	return buf.Bytes()
+}
+
+func decodePersistedNode(data []byte) (*persistedNode, error) {
+	if len(data) == 0 {
+		return nil, errors.New("persistentmerkletree: empty node encoding")
+	}
+	if data[0] == 0x00 {
+		return &persistedNode{isLeaf: true, leaf: append([]byte{}, data[1:]...)}, nil
+	}
+	r := bytes.NewReader(data[1:])
+	var leftCount int64
+	var leftLen int32
+	if err := binary.Read(r, binary.BigEndian, &leftCount); err != nil {
+		return nil, err
+	}
+	if err := binary.Read(r, binary.BigEndian, &leftLen); err != nil {
+		return nil, err
+	}
+	rest := data[1+8+4:]
+	if int32(len(rest)) < 2*leftLen {
+		return nil, errors.New("persistentmerkletree: truncated node encoding")
+	}
+	left := append([]byte{}, rest[:leftLen]...)
+	right := append([]byte{}, rest[leftLen:2*leftLen]...)
+	return &persistedNode{isLeaf: false, left: left, right: right, leftCount: int(leftCount)}, nil
+}
+
+// PersistentMerkleTree 把节点按哈希持久化到 Storage，而不是像 MerkleTree 那样把所有层都留在 TreeLayers 里，
+// 这样树可以比内存大，并且多个调用方可以各自拿着一个历史根并发生成见证而互不干扰（见 Snapshot）
+type PersistentMerkleTree struct {
+	store  Storage
+	scheme HashScheme
+	root   []byte
+}
+
+// NewPersistentMerkleTree 用给定的存储后端和哈希方案构造一棵空的持久化 Merkle 树
+func NewPersistentMerkleTree(store Storage, schemeID HashSchemeID) *PersistentMerkleTree {
+	return &PersistentMerkleTree{store: store, scheme: NewHashScheme(schemeID)}
+}
+
+// Build 构建一棵新树并把所有叶子和内部节点写入 Storage，返回根哈希
+// 和 MerkleTree.BuildTree 一样需要奇数层补齐，这里固定用复制最后一个节点的策略，和原实现保持一致
+func (t *PersistentMerkleTree) Build(leaves [][]byte, pathByte [][]byte) ([]byte, error) {
+	tx, err := t.store.NewTx()
+	if err != nil {
+		return nil, err
+	}
+
+	padded := append([][]byte{}, leaves...)
+	if len(padded)%2 != 0 {
+		padded = append(padded, padded[len(padded)-1])
+	}
+
+	type level struct {
+		hashes [][]byte
+		counts []int
+	}
+
+	hashedLeaves := make([][]byte, len(padded))
+	counts := make([]int, len(padded))
+	for i, leaf := range padded {
+		h := t.scheme.HashLeaf(leaf)
+		hashedLeaves[i] = h
+		counts[i] = 1
+		if err := tx.Put(h, encodePersistedNode(&persistedNode{isLeaf: true, leaf: leaf})); err != nil {
+			tx.Discard()
+			return nil, err
+		}
+	}
+
+	cur := level{hashes: hashedLeaves, counts: counts}
+	idx := 0
+	for len(cur.hashes) > 1 {
+		var next level
+		for i := 0; i < len(cur.hashes); i += 2 {
+			h := t.scheme.HashNode(pathByte[idx], cur.hashes[i], cur.hashes[i+1])
+			leftCount := cur.counts[i]
+			node := &persistedNode{left: cur.hashes[i], right: cur.hashes[i+1], leftCount: leftCount}
+			if err := tx.Put(h, encodePersistedNode(node)); err != nil {
+				tx.Discard()
+				return nil, err
+			}
+			next.hashes = append(next.hashes, h)
+			next.counts = append(next.counts, leftCount+cur.counts[i+1])
+		}
+		if len(next.hashes)%2 != 0 && len(next.hashes) != 1 {
+			next.hashes = append(next.hashes, next.hashes[len(next.hashes)-1])
+			next.counts = append(next.counts, next.counts[len(next.counts)-1])
+		}
+		cur = next
+		idx++
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	t.root = cur.hashes[0]
+	return t.root, nil
+}
+
+// GetProof 从某个根出发，只靠 Storage 里的节点哈希往下走就能找到目标叶子的认证路径，
+// 不需要像 MerkleTree.GetProof 那样依赖内存里完整的 TreeLayers。
+// 往下走天然是从根到叶子的顺序，但 MerkleTree.GetProof / MerkleProofCircuit.Path 都约定 siblings
+// 自底向上，所以返回前要整体反转一次，否则验证方按层号取的域分离标签和实际哈希的那一层对不上
+func (t *PersistentMerkleTree) GetProof(root []byte, leafIndex int) ([][]byte, error) {
+	var proof [][]byte
+	cur := root
+	for {
+		raw, err := t.store.Get(cur)
+		if err != nil {
+			return nil, err
+		}
+		node, err := decodePersistedNode(raw)
+		if err != nil {
+			return nil, err
+		}
+		if node.isLeaf {
+			reverseSiblings(proof)
+			return proof, nil
+		}
+		if leafIndex < node.leftCount {
+			proof = append(proof, node.right)
+			cur = node.left
+		} else {
+			proof = append(proof, node.left)
+			leafIndex -= node.leftCount
+			cur = node.right
+		}
+	}
+}
+
+// reverseSiblings 原地反转 proof，把根到叶子的顺序换成叶子到根的顺序
+func reverseSiblings(proof [][]byte) {
+	for i, j := 0, len(proof)-1; i < j; i, j = i+1, j-1 {
+		proof[i], proof[j] = proof[j], proof[i]
+	}
+}
+
+// Snapshot 返回一棵指向历史根的不可变视图：共享同一个 Storage 后端，多个 prover 可以各自拿着不同的 root
+// 并发生成见证而不用互相加锁或复制叶子数据
+func (t *PersistentMerkleTree) Snapshot(root []byte) *PersistentMerkleTree {
+	return &PersistentMerkleTree{store: t.store, scheme: t.scheme, root: root}
+}
+
+// Root 返回这棵树（或这个快照）当前指向的根哈希
+func (t *PersistentMerkleTree) Root() []byte {
+	return t.root
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+)
+
+// recomputePersistentProof 独立于 PersistentMerkleTree 重放 GetProof 返回的 siblings，验证它们确实能
+// 折算出 Build 返回的根，而不是信任树自己的记录。siblings 是自底向上的，所以从叶子哈希开始逐层往上折
+func recomputePersistentProof(scheme HashScheme, pathByte [][]byte, leaf []byte, leafIndex int, siblings [][]byte) []byte {
+	cur := scheme.HashLeaf(leaf)
+	pos := leafIndex
+	for level, sibling := range siblings {
+		if pos%2 == 0 {
+			cur = scheme.HashNode(pathByte[level], cur, sibling)
+		} else {
+			cur = scheme.HashNode(pathByte[level], sibling, cur)
+		}
+		pos /= 2
+	}
+	return cur
+}
+
+func TestPersistentMerkleTreeBuildAndGetProof(t *testing.T) {
+	leaves := make([][]byte, 4)
+	for i := range leaves {
+		b := make([]byte, dag_size)
+		b[dag_size-1] = byte(i + 1)
+		leaves[i] = b
+	}
+	_, pathByte := calculateLeafNodesInPath(2)
+
+	tree := NewPersistentMerkleTree(NewMemStorage(), HashMiMC)
+	root, err := tree.Build(leaves, pathByte)
+	if err != nil {
+		t.Fatalf("build: %v", err)
+	}
+
+	scheme := NewHashScheme(HashMiMC)
+	for i, leaf := range leaves {
+		proof, err := tree.GetProof(root, i)
+		if err != nil {
+			t.Fatalf("leaf %d: get proof: %v", i, err)
+		}
+		if got := recomputePersistentProof(scheme, pathByte, leaf, i, proof); !bytes.Equal(got, root) {
+			t.Fatalf("leaf %d: proof does not recompute to the tree root", i)
+		}
+	}
+}
+
+func TestPersistentMerkleTreeSnapshotIsolatesHistoricalRoots(t *testing.T) {
+	leaves4 := make([][]byte, 4)
+	for i := range leaves4 {
+		b := make([]byte, dag_size)
+		b[dag_size-1] = byte(i + 1)
+		leaves4[i] = b
+	}
+	_, pathByte4 := calculateLeafNodesInPath(2)
+
+	store := NewMemStorage()
+	tree := NewPersistentMerkleTree(store, HashMiMC)
+	root4, err := tree.Build(leaves4, pathByte4)
+	if err != nil {
+		t.Fatalf("build 4-leaf tree: %v", err)
+	}
+	snap := tree.Snapshot(root4)
+
+	leaves8 := append(append([][]byte{}, leaves4...), leaves4...)
+	_, pathByte8 := calculateLeafNodesInPath(3)
+	root8, err := tree.Build(leaves8, pathByte8)
+	if err != nil {
+		t.Fatalf("build 8-leaf tree: %v", err)
+	}
+	if bytes.Equal(root4, root8) {
+		t.Fatal("rebuilding with more leaves should change the root")
+	}
+
+	if snap.Root() == nil || !bytes.Equal(snap.Root(), root4) {
+		t.Fatal("Snapshot did not keep pointing at the root it was taken at")
+	}
+
+	scheme := NewHashScheme(HashMiMC)
+	proof, err := snap.GetProof(snap.Root(), 0)
+	if err != nil {
+		t.Fatalf("snapshot get proof: %v", err)
+	}
+	if got := recomputePersistentProof(scheme, pathByte4, leaves4[0], 0, proof); !bytes.Equal(got, root4) {
+		t.Fatal("snapshot proof does not recompute to the snapshotted root, even though the tree has since been rebuilt")
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"math/big"
+
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	groth16bn254 "github.com/consensys/gnark/backend/groth16/bn254"
+
+	"github.com/consensys/gnark/backend/groth16"
+	witness2 "github.com/consensys/gnark/backend/witness"
+)
+
+// ExportSolidityVerifier 把验证密钥导出成一份可以直接部署的 Solidity 合约源码，
+// 这样链下用本模块生成的 Groth16 证明就能交给链上合约验证，而不用再跑一遍 groth16.Verify。
+// gnark v0.15 生成的合约签名是 verifyProof(bytes calldata proof, uint256[N] calldata input)：
+// proof 是 EncodeProofForEVM 返回的那份不透明字节块，input 是同一次调用返回的标量数组，
+// 两者必须配对使用——这正是 EncodeProofForEVM 现在对齐的形状
+func ExportSolidityVerifier(vk groth16.VerifyingKey, w io.Writer) error {
+	return vk.ExportSolidity(w)
+}
+
+// EncodeProofForEVM 把 gnark 的 BN254 Groth16 证明和公开见证编码成 ExportSolidityVerifier 导出的
+// 合约 verifyProof(bytes calldata proof, uint256[N] calldata input) 期望的两个参数：
+// proofBytes 直接复用 gnark 自己的 Proof.MarshalSolidity()，字节布局和 vk.ExportSolidity 生成的
+// 合约要求的 proof 参数完全一致；inputs 是 input 数组里按顺序排好的每个标量，调用方可以直接拿它们
+// 和 proofBytes 一起去调用那份合约，或者用任意 ABI 编码库打包成真正的 calldata。
+// 旧实现把这两块拼成了 (uint256[2] a, uint256[2][2] b, uint256[2] c, uint256[] input) 的老式四参数
+// 布局，既没有 gnark 生成的合约接收的 bytes 参数，也不是后者期待的动态数组编码，两边根本对不上
+func EncodeProofForEVM(proof groth16.Proof, pub witness2.Witness) (proofBytes []byte, inputs []*big.Int, err error) {
+	bn254Proof, ok := proof.(*groth16bn254.Proof)
+	if !ok {
+		return nil, nil, errors.New("solidity: EncodeProofForEVM only supports BN254 proofs")
+	}
+	if len(bn254Proof.Commitments) > 0 {
+		return nil, nil, errors.New("solidity: EncodeProofForEVM does not support circuits with Pedersen commitments")
+	}
+
+	vector, ok := pub.Vector().(fr.Vector)
+	if !ok {
+		return nil, nil, errors.New("solidity: EncodeProofForEVM only supports BN254 public witnesses")
+	}
+
+	inputs = make([]*big.Int, len(vector))
+	for i := range vector {
+		inputs[i] = new(big.Int).SetBytes(vector[i].Marshal())
+	}
+
+	return bn254Proof.MarshalSolidity(), inputs, nil
+}
+
+// DecodeEVMProof 把 EncodeProofForEVM/MarshalSolidity 产出的 256 字节 proof 块还原成 (A, B, C) 三个
+// Groth16 证明点的坐标，供测试或链下校验直接比对，而不用盲目相信字节布局和注释描述的一致
+func DecodeEVMProof(proofBytes []byte) (a [2]*big.Int, b [2][2]*big.Int, c [2]*big.Int, err error) {
+	if len(proofBytes) != 256 {
+		return a, b, c, errors.New("solidity: DecodeEVMProof expects exactly 256 bytes (no Pedersen commitments)")
+	}
+
+	word := func(i int) *big.Int {
+		return new(big.Int).SetBytes(proofBytes[i*32 : (i+1)*32])
+	}
+
+	a = [2]*big.Int{word(0), word(1)}
+	// MarshalSolidity 写的是 Bs.X1|Bs.X0|Bs.Y1|Bs.Y0，也就是 (imag, real) 顺序
+	b = [2][2]*big.Int{
+		{word(2), word(3)},
+		{word(4), word(5)},
+	}
+	c = [2]*big.Int{word(6), word(7)}
+	return a, b, c, nil
+}
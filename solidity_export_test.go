@@ -0,0 +1,131 @@
+package main
+
+import (
+	"bytes"
+	"math"
+	"math/big"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/backend/groth16"
+	groth16bn254 "github.com/consensys/gnark/backend/groth16/bn254"
+	"github.com/consensys/gnark/frontend"
+	r1cs2 "github.com/consensys/gnark/frontend/cs/r1cs"
+)
+
+// TestEncodeProofForEVMMatchesMarshalSolidity 用一棵 4 叶子的 MiMC MerkleTree（比 SparseMerkleCircuit
+// 的 254 层电路轻量得多）走一遍真实的 Compile -> Setup -> Prove，然后验证 EncodeProofForEVM 产出的
+// proofBytes 跟 gnark 自己的 Proof.MarshalSolidity() 逐字节一致，DecodeEVMProof 能把它还原回
+// 证明本身的 Ar/Bs/Krs 坐标——这正是 ExportSolidityVerifier 生成的合约所期望的同一份编码
+func TestEncodeProofForEVMMatchesMarshalSolidity(t *testing.T) {
+	leafCount := 4
+	leaves := make([][]byte, leafCount)
+	for i := range leaves {
+		leaves[i] = make([]byte, dag_size)
+		leaves[i][dag_size-1] = byte(i + 1)
+	}
+	path, pathByte := calculateLeafNodesInPath(int(math.Log2(float64(leafCount))))
+
+	tree := MerkleTree{Leaves: leaves}
+	tree.BuildTree(path, pathByte)
+	root := tree.GetRoot()
+
+	const targetLeaf = 0
+	proofPath := tree.GetProof(targetLeaf)
+
+	var circuit MerkleProofCircuit
+	circuit.Leaf = make([]frontend.Variable, len(leaves[targetLeaf])/32)
+	circuit.Path = make([]frontend.Variable, len(proofPath))
+	circuit.LeafNum = make([]frontend.Variable, len(proofPath))
+	circuit.Helper = make([]frontend.Variable, len(proofPath))
+	circuit.LeafNUm_byte = make([]frontend.Variable, len(proofPath))
+
+	var assignment MerkleProofCircuit
+	assignment.Leaf = make([]frontend.Variable, len(leaves[targetLeaf])/32)
+	for i := 0; i < len(leaves[targetLeaf]); i += 32 {
+		assignment.Leaf[i/32] = leaves[targetLeaf][i : i+32]
+	}
+	assignment.LeafIndex = targetLeaf
+	assignment.RootHash = root
+	assignment.Path = make([]frontend.Variable, len(proofPath))
+	assignment.LeafNum = make([]frontend.Variable, len(proofPath))
+	assignment.Helper = make([]frontend.Variable, len(proofPath))
+	assignment.LeafNUm_byte = make([]frontend.Variable, len(proofPath))
+	for i := 0; i < len(proofPath); i++ {
+		assignment.Path[i] = proofPath[i]
+		assignment.LeafNum[i] = path[i]
+		assignment.Helper[i] = targetLeaf >> i & 1
+		assignment.LeafNUm_byte[i] = pathByte[i]
+	}
+
+	r1cs, err := frontend.Compile(ecc.BN254.ScalarField(), r1cs2.NewBuilder, &circuit)
+	if err != nil {
+		t.Fatalf("compile: %v", err)
+	}
+	pk, _, err := groth16.Setup(r1cs)
+	if err != nil {
+		t.Fatalf("setup: %v", err)
+	}
+
+	witness, err := frontend.NewWitness(&assignment, ecc.BN254.ScalarField())
+	if err != nil {
+		t.Fatalf("witness: %v", err)
+	}
+	publicWitness, err := witness.Public()
+	if err != nil {
+		t.Fatalf("public witness: %v", err)
+	}
+
+	proof, err := groth16.Prove(r1cs, pk, witness)
+	if err != nil {
+		t.Fatalf("prove: %v", err)
+	}
+
+	proofBytes, inputs, err := EncodeProofForEVM(proof, publicWitness)
+	if err != nil {
+		t.Fatalf("EncodeProofForEVM: %v", err)
+	}
+
+	bn254Proof, ok := proof.(*groth16bn254.Proof)
+	if !ok {
+		t.Fatal("expected a BN254 proof")
+	}
+	if !bytes.Equal(proofBytes, bn254Proof.MarshalSolidity()) {
+		t.Fatal("EncodeProofForEVM's proof bytes do not match Proof.MarshalSolidity(), which is exactly what the contract ExportSolidityVerifier emits expects as its `proof` argument")
+	}
+
+	if len(inputs) != 1 {
+		t.Fatalf("expected exactly 1 public input (RootHash), got %d", len(inputs))
+	}
+
+	a, b, c, err := DecodeEVMProof(proofBytes)
+	if err != nil {
+		t.Fatalf("DecodeEVMProof: %v", err)
+	}
+
+	wantX := new(big.Int).SetBytes(bn254Proof.Ar.X.Marshal())
+	wantY := new(big.Int).SetBytes(bn254Proof.Ar.Y.Marshal())
+	if a[0].Cmp(wantX) != 0 || a[1].Cmp(wantY) != 0 {
+		t.Fatal("decoded A does not match the real proof's Ar point")
+	}
+
+	wantBX1 := new(big.Int).SetBytes(bn254Proof.Bs.X.A1.Marshal())
+	wantBX0 := new(big.Int).SetBytes(bn254Proof.Bs.X.A0.Marshal())
+	wantBY1 := new(big.Int).SetBytes(bn254Proof.Bs.Y.A1.Marshal())
+	wantBY0 := new(big.Int).SetBytes(bn254Proof.Bs.Y.A0.Marshal())
+	if b[0][0].Cmp(wantBX1) != 0 || b[0][1].Cmp(wantBX0) != 0 || b[1][0].Cmp(wantBY1) != 0 || b[1][1].Cmp(wantBY0) != 0 {
+		t.Fatal("decoded B does not match the real proof's Bs point")
+	}
+
+	wantCX := new(big.Int).SetBytes(bn254Proof.Krs.X.Marshal())
+	wantCY := new(big.Int).SetBytes(bn254Proof.Krs.Y.Marshal())
+	if c[0].Cmp(wantCX) != 0 || c[1].Cmp(wantCY) != 0 {
+		t.Fatal("decoded C does not match the real proof's Krs point")
+	}
+}
+
+func TestDecodeEVMProofRejectsWrongLength(t *testing.T) {
+	if _, _, _, err := DecodeEVMProof(make([]byte, 100)); err == nil {
+		t.Fatal("expected an error for a proof blob that isn't 256 bytes")
+	}
+}
@@ -0,0 +1,294 @@
+package main
+
+import (
+	"bytes"
+
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/std/hash/mimc"
+)
+
+// 稀疏 Merkle 树固定深度，键先经过 MiMC 哈希再按比特位展开路径
+// 254 取自 BN254 标量域的安全比特数，保证键空间不会发生域内回绕
+const smtDepth = 254
+
+// emptySubtreeRoots[i] 是第 i 层（从叶子层往上数，0 为叶子层）空子树的哈希
+// 该表在包初始化时一次性算好，电路和树构建代码都直接查表替换缺失的兄弟节点
+var emptySubtreeRoots [smtDepth + 1][]byte
+
+func init() {
+	emptySubtreeRoots[0] = hashFunction([]byte{0x00})
+	for i := 1; i <= smtDepth; i++ {
+		emptySubtreeRoots[i] = hashFunctionMulti(nodeDomainTag, emptySubtreeRoots[i-1], emptySubtreeRoots[i-1])
+	}
+}
+
+// smtNode 是一个已写入的叶子：键、值和它自己的哈希
+type smtNode struct {
+	hash  []byte
+	key   []byte
+	value []byte
+}
+
+// smtSubtree 记录路径前缀（从根数起的若干个比特）对应子树的哈希，以及——如果这棵子树下面只挂了
+// 唯一一个叶子——指向那个叶子，这样 GetNonMembershipProof 才能判断某条路径是"压根没人"还是"被另一个
+// 键独占"，而不是瞎猜一个已存的叶子
+type smtSubtree struct {
+	hash    []byte
+	sole    *smtNode // 子树下唯一的叶子；子树下有两个及以上叶子时为 nil
+}
+
+// SparseMerkleTree 是按哈希键寻址的稀疏 Merkle 树，只保存真正被写入过路径上的子树
+// 未被触达的分支不占用内存，查询时用 emptySubtreeRoots 里对应层的空根代替
+type SparseMerkleTree struct {
+	subtrees map[string]*smtSubtree // key: 路径前缀（'0'/'1' 组成的比特串），value：该前缀下的子树信息
+	leaves   map[string]*smtNode    // key: 原始 key 的字节串，value：对应叶子，方便成员证明直接取值
+}
+
+// NewSparseMerkleTree 构造一棵空的稀疏 Merkle 树
+func NewSparseMerkleTree() *SparseMerkleTree {
+	return &SparseMerkleTree{
+		subtrees: make(map[string]*smtSubtree),
+		leaves:   make(map[string]*smtNode),
+	}
+}
+
+// keyPath 将键哈希为 smtDepth 位的路径，bit[0] 对应从根往下的第一步
+func keyPath(key []byte) []int {
+	h := hashFunction(key)
+	bits := make([]int, smtDepth)
+	for i := 0; i < smtDepth; i++ {
+		byteIndex := i / 8
+		bitIndex := uint(i % 8)
+		if byteIndex >= len(h) {
+			bits[i] = 0
+			continue
+		}
+		bits[i] = int((h[byteIndex] >> bitIndex) & 1)
+	}
+	return bits
+}
+
+// prefixPath 把 bits[0:length] 转成一个由 '0'/'1' 组成的字符串，用作 subtrees 的 key
+func prefixPath(bits []int, length int) string {
+	buf := make([]byte, length)
+	for i := 0; i < length; i++ {
+		if bits[i] == 1 {
+			buf[i] = '1'
+		} else {
+			buf[i] = '0'
+		}
+	}
+	return string(buf)
+}
+
+// siblingChar 返回和 bit 相反的路径字符，用来拼出兄弟子树的前缀
+func siblingChar(bit int) byte {
+	if bit == 0 {
+		return '1'
+	}
+	return '0'
+}
+
+// leafHash 对叶子做域分离哈希，和内部节点区分开，避免第二原像攻击
+func (t *SparseMerkleTree) leafHash(key, value []byte) []byte {
+	return hashFunctionMulti(leafDomainTag, key, value)
+}
+
+// Put 在稀疏树中插入或更新一个键值对。和只缓存"最近一次写入"的旧实现不同，这里沿着 key 的比特路径
+// 把每一级子树的哈希都重新折算出来：自底向上每一层要么和另一条已写入的子树合并（从 subtrees 里取它的
+// 哈希），要么和 emptySubtreeRoots 里对应层的空哈希合并，不会把之前写过的键覆盖掉
+func (t *SparseMerkleTree) Put(key, value []byte) {
+	bits := keyPath(key)
+	leaf := &smtNode{key: append([]byte{}, key...), value: append([]byte{}, value...), hash: t.leafHash(key, value)}
+	t.leaves[string(key)] = leaf
+
+	cur := leaf.hash
+	t.markPrefix(bits, smtDepth, leaf, cur)
+
+	for level := smtDepth - 1; level >= 0; level-- {
+		parentPrefix := prefixPath(bits, level)
+		siblingPrefix := parentPrefix + string(siblingChar(bits[level]))
+
+		sibling := emptySubtreeRoots[smtDepth-1-level]
+		if st, ok := t.subtrees[siblingPrefix]; ok {
+			sibling = st.hash
+		}
+
+		if bits[level] == 0 {
+			cur = hashFunctionMulti(nodeDomainTag, cur, sibling)
+		} else {
+			cur = hashFunctionMulti(nodeDomainTag, sibling, cur)
+		}
+		t.markPrefix(bits, level, leaf, cur)
+	}
+}
+
+// markPrefix 更新 bits[0:length] 这个前缀对应子树的哈希，并维护它的"唯一叶子"标记：
+// 第一次写入时这棵子树下只有这一个叶子；如果后来另一个键的路径也落进了同一个前缀，就把 sole 清空，
+// 表示这棵子树已经分叉，不能再把它当成某一个叶子的专属子树
+func (t *SparseMerkleTree) markPrefix(bits []int, length int, leaf *smtNode, hash []byte) {
+	prefix := prefixPath(bits, length)
+	st, ok := t.subtrees[prefix]
+	if !ok {
+		t.subtrees[prefix] = &smtSubtree{hash: hash, sole: leaf}
+		return
+	}
+	if st.sole != nil {
+		if bytes.Equal(st.sole.key, leaf.key) {
+			st.sole = leaf // 同一个键原地更新取值，子树仍然只有这一个叶子
+		} else {
+			st.sole = nil // 第二个不同的键落进了这棵子树，从此分叉，不会再变回"只有一个叶子"
+		}
+	}
+	st.hash = hash
+}
+
+// Root 返回当前稀疏树的根哈希
+func (t *SparseMerkleTree) Root() []byte {
+	if st, ok := t.subtrees[""]; ok {
+		return st.hash
+	}
+	return emptySubtreeRoots[smtDepth]
+}
+
+// SMTMembershipProof 证明某个键确实存储着给定的值
+type SMTMembershipProof struct {
+	Key      []byte
+	Value    []byte
+	Siblings [][]byte // 自底向上，长度为 smtDepth
+}
+
+// SMTNonMembershipKind 区分两种不存在的终止方式
+type SMTNonMembershipKind int
+
+const (
+	// SMTEmptyTermination 路径在某一层碰到空子树，说明该键从未被写入
+	SMTEmptyTermination SMTNonMembershipKind = iota
+	// SMTConflictTermination 路径落在另一个已存在叶子独占的子树里，需要揭示冲突叶子
+	SMTConflictTermination
+)
+
+// SMTNonMembershipProof 证明某个键不存在于树中
+type SMTNonMembershipProof struct {
+	Key        []byte
+	Kind       SMTNonMembershipKind
+	Siblings   [][]byte
+	OtherKey   []byte // 仅在 SMTConflictTermination 下有效
+	OtherValue []byte
+}
+
+// siblingsAlong 自底向上收集 key 这条路径上每一层的兄弟哈希，遇到没写过的子树就用 emptySubtreeRoots 代替
+func (t *SparseMerkleTree) siblingsAlong(bits []int) [][]byte {
+	siblings := make([][]byte, smtDepth)
+	for level := smtDepth - 1; level >= 0; level-- {
+		parentPrefix := prefixPath(bits, level)
+		siblingPrefix := parentPrefix + string(siblingChar(bits[level]))
+
+		sibling := emptySubtreeRoots[smtDepth-1-level]
+		if st, ok := t.subtrees[siblingPrefix]; ok {
+			sibling = st.hash
+		}
+		siblings[smtDepth-1-level] = sibling
+	}
+	return siblings
+}
+
+// GetMembershipProof 为已写入的 key 构造成员证明，调用方需自行保证 key 存在
+func (t *SparseMerkleTree) GetMembershipProof(key []byte) *SMTMembershipProof {
+	leaf := t.leaves[string(key)]
+	bits := keyPath(key)
+	return &SMTMembershipProof{Key: key, Value: leaf.value, Siblings: t.siblingsAlong(bits)}
+}
+
+// GetNonMembershipProof 为未写入的 key 构造非成员证明，调用方需自行保证 key 不存在。
+// 沿着 key 自己的比特路径从根往下走：一旦走到的子树压根没被写入过，就是"空终止"；
+// 一旦走到的子树下面只独占着另外一个叶子（用 smtSubtree.sole 判断，而不是从 map 里随便挑一个),
+// 就是"冲突终止"，把那个叶子的 key/value 带出来，好让电路断言 Key != OtherKey
+func (t *SparseMerkleTree) GetNonMembershipProof(key []byte) *SMTNonMembershipProof {
+	bits := keyPath(key)
+	siblings := t.siblingsAlong(bits)
+
+	for depth := 1; depth <= smtDepth; depth++ {
+		prefix := prefixPath(bits, depth)
+		st, ok := t.subtrees[prefix]
+		if !ok {
+			return &SMTNonMembershipProof{Key: key, Kind: SMTEmptyTermination, Siblings: siblings}
+		}
+		if st.sole != nil {
+			// 冲突终止揭示的是真实占用者自己的认证路径：占用者的 bits 在分叉点（这里的 depth）之前
+			// 必然和查询 key 完全一致（否则两者根本不会落进同一棵子树），但分叉点之后、更靠近叶子的那些
+			// 层只有占用者真正走过，必须按占用者自己的 key 重新沿路径折算，继续套用查询 key 的 bits
+			// 会在那些层查到空子树，折算不出占用者真正存入树里的那个哈希
+			return &SMTNonMembershipProof{
+				Key:        key,
+				Kind:       SMTConflictTermination,
+				Siblings:   t.siblingsAlong(keyPath(st.sole.key)),
+				OtherKey:   st.sole.key,
+				OtherValue: st.sole.value,
+			}
+		}
+	}
+	return &SMTNonMembershipProof{Key: key, Kind: SMTEmptyTermination, Siblings: siblings}
+}
+
+// SparseMerkleCircuit 在 gnark 电路内验证成员/非成员证明
+// Membership 为 1 时校验 (Key, Value) 确实哈希到 RootHash；为 0 时校验非成员的两种终止方式之一
+type SparseMerkleCircuit struct {
+	RootHash   frontend.Variable   `gnark:",public"`
+	Key        frontend.Variable   // 私有：待证明的键（已哈希为路径比特）
+	Value      frontend.Variable   // 私有：成员证明下的叶子取值
+	Siblings   []frontend.Variable // 私有：自底向上 smtDepth 个兄弟哈希
+	PathBits   []frontend.Variable // 私有：Key 哈希后的比特路径，bit[0] 为最底层方向
+	Membership frontend.Variable   // 公开：1 表示成员证明，0 表示非成员证明
+	Kind       frontend.Variable   // 私有：非成员证明时的终止类型，0 空终止，1 冲突终止
+	OtherKey   frontend.Variable   // 私有：冲突终止时被占用叶子的键
+	OtherValue frontend.Variable   // 私有：冲突终止时被占用叶子的值
+}
+
+// Define 实现 frontend.Circuit，按成员/非成员两种模式重算根并与公开 RootHash 比较
+// 三种叶子哈希都必须和树外 leafHash/emptySubtreeRoots[0] 用同一套域分离标签，否则电路算出来的
+// 根和 SparseMerkleTree 自己维护的根对不上：
+//   - 成员模式： H(leafDomainTag, key, value)，对应 leafHash
+//   - 非成员·空终止： H(leafDomainTag)，不掺入任何 key/value，对应 emptySubtreeRoots[0] = hashFunction({0x00})
+//   - 非成员·冲突终止： H(leafDomainTag, otherKey, otherValue)，并断言 Key != OtherKey
+func (circuit *SparseMerkleCircuit) Define(api frontend.API) error {
+	mimcHash, _ := mimc.NewMiMC(api)
+
+	mimcHash.Write(leafDomainTag[0], circuit.Key, circuit.Value)
+	memberLeaf := mimcHash.Sum()
+	mimcHash.Reset()
+
+	mimcHash.Write(leafDomainTag[0])
+	emptyLeaf := mimcHash.Sum()
+	mimcHash.Reset()
+
+	mimcHash.Write(leafDomainTag[0], circuit.OtherKey, circuit.OtherValue)
+	conflictLeaf := mimcHash.Sum()
+	mimcHash.Reset()
+
+	isConflict := api.And(api.Sub(1, circuit.Membership), circuit.Kind)
+	keysDiffer := api.Sub(circuit.Key, circuit.OtherKey)
+	api.AssertIsDifferent(api.Select(isConflict, keysDiffer, 1), 0)
+
+	nonMemberLeaf := api.Select(circuit.Kind, conflictLeaf, emptyLeaf)
+	leaf := api.Select(circuit.Membership, memberLeaf, nonMemberLeaf)
+
+	computed := leaf
+	for level := 0; level < len(circuit.Siblings); level++ {
+		sibling := circuit.Siblings[level]
+		bit := circuit.PathBits[level]
+
+		mimcHash.Write(nodeDomainTag[0], computed, sibling)
+		leftParent := mimcHash.Sum()
+		mimcHash.Reset()
+
+		mimcHash.Write(nodeDomainTag[0], sibling, computed)
+		rightParent := mimcHash.Sum()
+		mimcHash.Reset()
+
+		computed = api.Select(bit, rightParent, leftParent)
+	}
+
+	api.AssertIsEqual(computed, circuit.RootHash)
+	return nil
+}
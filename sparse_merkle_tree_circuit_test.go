@@ -0,0 +1,146 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/backend/groth16"
+	"github.com/consensys/gnark/frontend"
+	r1cs2 "github.com/consensys/gnark/frontend/cs/r1cs"
+)
+
+// newSMTCircuitShape 分配一个 smtDepth 宽的 SparseMerkleCircuit 骨架，成员/非成员两种见证都复用它
+func newSMTCircuitShape() SparseMerkleCircuit {
+	return SparseMerkleCircuit{
+		Siblings: make([]frontend.Variable, smtDepth),
+		PathBits: make([]frontend.Variable, smtDepth),
+	}
+}
+
+// assignSiblingsAndBits 把 bits/siblings 填进电路赋值里，非成员证明和成员证明共用同一段填法。
+// Siblings（及电路里走的 PathBits）都是自底向上的：circuit 下标 i 对应 siblingsAlong 里的
+// smtDepth-1-level == i，也就是 bits[smtDepth-1-i]，和 recomputeSMTRoot 的约定一致
+func assignSiblingsAndBits(assignment *SparseMerkleCircuit, bits []int, siblings [][]byte) {
+	for level := 0; level < smtDepth; level++ {
+		assignment.Siblings[level] = siblings[level]
+		assignment.PathBits[level] = bits[smtDepth-1-level]
+	}
+}
+
+// provesSMTCircuit 跑一次完整的 Compile -> Setup -> Prove -> Verify，确认这组赋值在真实证明系统里也站得住，
+// 而不只是满足 frontend.Variable 层面的代数约束
+func provesSMTCircuit(t *testing.T, circuit, assignment *SparseMerkleCircuit) {
+	t.Helper()
+
+	r1cs, err := frontend.Compile(ecc.BN254.ScalarField(), r1cs2.NewBuilder, circuit)
+	if err != nil {
+		t.Fatalf("compile failed: %v", err)
+	}
+
+	pk, vk, err := groth16.Setup(r1cs)
+	if err != nil {
+		t.Fatalf("setup failed: %v", err)
+	}
+
+	witness, err := frontend.NewWitness(assignment, ecc.BN254.ScalarField())
+	if err != nil {
+		t.Fatalf("witness failed: %v", err)
+	}
+
+	publicWitness, err := witness.Public()
+	if err != nil {
+		t.Fatalf("public witness failed: %v", err)
+	}
+
+	proof, err := groth16.Prove(r1cs, pk, witness)
+	if err != nil {
+		t.Fatalf("prove failed: %v", err)
+	}
+
+	if err := groth16.Verify(proof, vk, publicWitness); err != nil {
+		t.Fatalf("verify failed: %v", err)
+	}
+}
+
+func TestSparseMerkleCircuitProvesRealMembershipProof(t *testing.T) {
+	tree := NewSparseMerkleTree()
+	tree.Put([]byte("alice"), []byte("100"))
+	tree.Put([]byte("bob"), []byte("200"))
+
+	proof := tree.GetMembershipProof([]byte("alice"))
+	bits := keyPath([]byte("alice"))
+
+	circuit := newSMTCircuitShape()
+	assignment := newSMTCircuitShape()
+
+	assignment.RootHash = tree.Root()
+	assignment.Key = []byte("alice")
+	assignment.Value = proof.Value
+	assignment.Membership = 1
+	assignment.Kind = 0
+	assignment.OtherKey = 0
+	assignment.OtherValue = 0
+	assignSiblingsAndBits(&assignment, bits, proof.Siblings)
+
+	provesSMTCircuit(t, &circuit, &assignment)
+}
+
+func TestSparseMerkleCircuitProvesRealNonMembershipEmptyTermination(t *testing.T) {
+	tree := NewSparseMerkleTree()
+	tree.Put([]byte("alice"), []byte("100"))
+
+	// 不像冲突终止那样固定选一个已知落进同一子树的候选 key，这里要找一个在第一层就没有
+	// 任何已写入子树的 key，所以挨个试直到真的拿到空终止
+	var key []byte
+	var proof *SMTNonMembershipProof
+	for i := 0; ; i++ {
+		candidate := []byte("ghost" + string(rune('a'+i)))
+		p := tree.GetNonMembershipProof(candidate)
+		if p.Kind == SMTEmptyTermination {
+			key, proof = candidate, p
+			break
+		}
+	}
+	bits := keyPath(key)
+
+	circuit := newSMTCircuitShape()
+	assignment := newSMTCircuitShape()
+
+	assignment.RootHash = tree.Root()
+	assignment.Key = key
+	assignment.Value = 0
+	assignment.Membership = 0
+	assignment.Kind = 0
+	assignment.OtherKey = 0
+	assignment.OtherValue = 0
+	assignSiblingsAndBits(&assignment, bits, proof.Siblings)
+
+	provesSMTCircuit(t, &circuit, &assignment)
+}
+
+func TestSparseMerkleCircuitProvesRealNonMembershipConflictTermination(t *testing.T) {
+	tree := NewSparseMerkleTree()
+	tree.Put([]byte("k1"), []byte("v1"))
+
+	proof := tree.GetNonMembershipProof([]byte("cand1"))
+	if proof.Kind != SMTConflictTermination {
+		t.Fatalf("expected conflict termination, got %v", proof.Kind)
+	}
+	// 冲突终止折算用的是真实占用者（OtherKey）自己的路径，不是查询 key 的路径，见
+	// GetNonMembershipProof 里 Siblings 字段的注释
+	bits := keyPath(proof.OtherKey)
+
+	circuit := newSMTCircuitShape()
+	assignment := newSMTCircuitShape()
+
+	assignment.RootHash = tree.Root()
+	assignment.Key = []byte("cand1")
+	assignment.Value = 0
+	assignment.Membership = 0
+	assignment.Kind = 1
+	assignment.OtherKey = proof.OtherKey
+	assignment.OtherValue = proof.OtherValue
+	assignSiblingsAndBits(&assignment, bits, proof.Siblings)
+
+	provesSMTCircuit(t, &circuit, &assignment)
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+)
+
+// recomputeSMTRoot 独立于 SparseMerkleTree 重新走一遍 Put 里同样的自底向上折算，用来验证某个
+// 成员证明带出来的 Siblings 确实能推出树当前的根，而不是信任树自己算出来的值
+func recomputeSMTRoot(key, value []byte, siblings [][]byte) []byte {
+	t := &SparseMerkleTree{}
+	bits := keyPath(key)
+	cur := t.leafHash(key, value)
+	for level := smtDepth - 1; level >= 0; level-- {
+		sibling := siblings[smtDepth-1-level]
+		if bits[level] == 0 {
+			cur = hashFunctionMulti(nodeDomainTag, cur, sibling)
+		} else {
+			cur = hashFunctionMulti(nodeDomainTag, sibling, cur)
+		}
+	}
+	return cur
+}
+
+func TestSparseMerkleTreeMultiLeafMembership(t *testing.T) {
+	tree := NewSparseMerkleTree()
+	keys := [][]byte{[]byte("alice"), []byte("bob"), []byte("carol")}
+	values := [][]byte{[]byte("100"), []byte("200"), []byte("300")}
+	for i := range keys {
+		tree.Put(keys[i], values[i])
+	}
+
+	for i := range keys {
+		proof := tree.GetMembershipProof(keys[i])
+		if !bytes.Equal(proof.Value, values[i]) {
+			t.Fatalf("key %s: expected value %s, got %s", keys[i], values[i], proof.Value)
+		}
+		if got := recomputeSMTRoot(keys[i], proof.Value, proof.Siblings); !bytes.Equal(got, tree.Root()) {
+			t.Fatalf("membership proof for %s does not recompute to the tree root", keys[i])
+		}
+	}
+}
+
+func TestSparseMerkleTreeSecondPutDoesNotEraseFirst(t *testing.T) {
+	tree := NewSparseMerkleTree()
+	tree.Put([]byte("k1"), []byte("v1"))
+	rootAfterFirst := tree.Root()
+	tree.Put([]byte("k2"), []byte("v2"))
+
+	if bytes.Equal(tree.Root(), rootAfterFirst) {
+		t.Fatal("root did not change after a second Put, the first key's contribution was lost")
+	}
+
+	proof := tree.GetMembershipProof([]byte("k1"))
+	if got := recomputeSMTRoot([]byte("k1"), []byte("v1"), proof.Siblings); !bytes.Equal(got, tree.Root()) {
+		t.Fatal("first key's membership proof broke after a second Put")
+	}
+}
+
+func TestSparseMerkleTreeNonMembershipOnEmptyTree(t *testing.T) {
+	tree := NewSparseMerkleTree()
+	proof := tree.GetNonMembershipProof([]byte("ghost"))
+	if proof.Kind != SMTEmptyTermination {
+		t.Fatalf("expected empty termination on an empty tree, got %v", proof.Kind)
+	}
+}
+
+func TestSparseMerkleTreeNonMembershipConflictRevealsRealOccupant(t *testing.T) {
+	tree := NewSparseMerkleTree()
+	tree.Put([]byte("k1"), []byte("v1"))
+
+	// cand1 shares k1's root-level path bit, so the query necessarily lands in k1's
+	// sole-occupied subtree and must terminate with a conflict against k1, not an
+	// arbitrary entry from the tree's node map.
+	proof := tree.GetNonMembershipProof([]byte("cand1"))
+	if proof.Kind != SMTConflictTermination {
+		t.Fatalf("expected conflict termination, got %v", proof.Kind)
+	}
+	if !bytes.Equal(proof.OtherKey, []byte("k1")) {
+		t.Fatalf("conflict termination revealed %q, want the actual occupant k1", proof.OtherKey)
+	}
+}
+
+func TestSparseMerkleTreeNonMembershipRejectsUnrelatedOccupant(t *testing.T) {
+	tree := NewSparseMerkleTree()
+	tree.Put([]byte("k1"), []byte("v1"))
+	tree.Put([]byte("k2"), []byte("v2"))
+
+	proof := tree.GetNonMembershipProof([]byte("k3"))
+	if proof.Kind != SMTConflictTermination {
+		return
+	}
+	if !bytes.Equal(proof.OtherKey, []byte("k1")) && !bytes.Equal(proof.OtherKey, []byte("k2")) {
+		t.Fatalf("conflict termination must reveal a key actually stored in the tree, got %q", proof.OtherKey)
+	}
+}
@@ -0,0 +1,240 @@
+package main
+
+import "errors"
+
+// OddLeafPolicy 决定叶子数量为奇数时如何补齐最后一层
+type OddLeafPolicy int
+
+const (
+	// DuplicateLast 复制最后一个叶子，是当前 MerkleTree.BuildTree 的历史行为
+	// 注意：这会带来 CVE-2012-2459 式的可塑性，不应再作为默认值使用
+	DuplicateLast OddLeafPolicy = iota
+	// EmptyHash 用固定的空哈希补齐，不会引入可塑性
+	EmptyHash
+	// Error 拒绝奇数个叶子，把补齐策略的决定权交还给调用方
+	Error
+)
+
+var errOddLeafCount = errors.New("stacktree: odd leaf count and OddLeafPolicy is Error")
+
+// errNotProofStable 在叶子数量不是 2 的幂时由 ProofFor 返回：Root() 会对栈里剩下的悬空层做一次
+// 不落盘的虚拟补齐才能算出根，而这次补齐用到的填充兄弟从未写进 leafSiblings，所以这种状态下任何
+// 叶子的 ProofFor 结果都会在补齐发生的那一层之后断掉，重放不出 Root() 真正返回的根
+var errNotProofStable = errors.New("stacktree: leaf count is not a power of two, tree is not proof-stable")
+
+// stackLevel 记录栈中某一层未配对的那个哈希
+type stackLevel struct {
+	hash []byte
+	full bool // full 为 false 表示该层还在等待配对的另一半
+}
+
+// StackTree 是仿 go-ethereum stack-trie 的增量 Merkle 树构建器
+// 只保留最多 log2(N) 个未完成子树的哈希，不像 MerkleTree 那样把所有叶子和层都留在内存里
+type StackTree struct {
+	hash     func([]byte) []byte
+	pathByte [][]byte // 与 calculateLeafNodesInPath 产出的 path_byte 含义一致，逐层域分离
+	policy   OddLeafPolicy
+
+	stack     []stackLevel // stack[0] 是叶子层，往上每一层下标加一
+	stackBase []int        // stackBase[level] 是 stack[level] 这个待配对子树覆盖的第一个叶子下标，仅在 stack[level].full 时有意义
+	leaves    int
+	lastLeaf  []byte // 记录最近一个叶子，供 DuplicateLast 策略补齐使用
+
+	// leafSiblings 记录每个叶子在真正发生过合并的那些层上，实际配对到的兄弟哈希；
+	// 只有在 insert 把两棵子树真正合并的那一刻，这一层的兄弟是谁才是确定的，
+	// 所以必须在合并发生时就按叶子下标登记下来，而不能等到事后再去读随时会被合并清空的 stack
+	leafSiblings map[int][][]byte
+}
+
+// stackTreeSnapshot 是 Checkpoint 的返回值，Restore 只认自己吐出来的快照
+type stackTreeSnapshot struct {
+	stack        []stackLevel
+	stackBase    []int
+	leaves       int
+	lastLeaf     []byte
+	leafSiblings map[int][][]byte
+}
+
+// NewStackTree 构造一棵增量 Merkle 树
+// hash 是叶子/内部节点共用的哈希函数，path_byte 由 calculateLeafNodesInPath 提供，逐层做域分离
+func NewStackTree(hash func([]byte) []byte, path_byte [][]byte) *StackTree {
+	return &StackTree{hash: hash, pathByte: path_byte, policy: EmptyHash}
+}
+
+// WithOddLeafPolicy 设置奇数叶子补齐策略，默认是 EmptyHash
+func (t *StackTree) WithOddLeafPolicy(policy OddLeafPolicy) *StackTree {
+	t.policy = policy
+	return t
+}
+
+// Push 追加一个叶子，并沿着栈逐层合并已经配对好的兄弟节点
+func (t *StackTree) Push(leaf []byte) error {
+	base := t.leaves
+	t.leaves++
+	t.lastLeaf = leaf
+	return t.insert(0, t.hash(leaf), base)
+}
+
+// insert 把覆盖 [base, base+2^level) 这段叶子的 hash 放到第 level 层；如果该层已经有一个待配对的哈希，
+// 就把两边都记录进 leafSiblings（各自把对方当作这一层的兄弟），合并后继续往上插
+func (t *StackTree) insert(level int, h []byte, base int) error {
+	for level >= len(t.stack) {
+		t.stack = append(t.stack, stackLevel{})
+		t.stackBase = append(t.stackBase, 0)
+	}
+
+	if !t.stack[level].full {
+		t.stack[level] = stackLevel{hash: h, full: true}
+		t.stackBase[level] = base
+		return nil
+	}
+
+	left := t.stack[level].hash
+	leftBase := t.stackBase[level]
+	size := 1 << uint(level)
+	t.recordSiblings(leftBase, size, h)
+	t.recordSiblings(base, size, left)
+
+	combined := append(append(append([]byte{}, t.pathByteAt(level)...), left...), h...)
+	parent := t.hash(combined)
+	t.stack[level] = stackLevel{}
+	return t.insert(level+1, parent, leftBase)
+}
+
+// recordSiblings 把 sibling 追加到 [base, base+size) 范围内每个叶子的兄弟哈希历史里，
+// 对应它们在这一层的合并刚刚确定下来的兄弟
+func (t *StackTree) recordSiblings(base, size int, sibling []byte) {
+	if t.leafSiblings == nil {
+		t.leafSiblings = make(map[int][][]byte)
+	}
+	for idx := base; idx < base+size; idx++ {
+		t.leafSiblings[idx] = append(t.leafSiblings[idx], sibling)
+	}
+}
+
+// pathByteAt 按层号取域分离标签，超出已有表长时复用最后一项
+func (t *StackTree) pathByteAt(level int) []byte {
+	if level < len(t.pathByte) {
+		return t.pathByte[level]
+	}
+	if len(t.pathByte) == 0 {
+		return nil
+	}
+	return t.pathByte[len(t.pathByte)-1]
+}
+
+// Root 汇总栈里剩下的未配对哈希得到当前根，不改变内部状态
+// 奇数个叶子时按 OddLeafPolicy 补齐最顶上那个悬空节点
+func (t *StackTree) Root() ([]byte, error) {
+	if t.leaves == 0 {
+		return nil, errors.New("stacktree: empty tree has no root")
+	}
+
+	// 拷贝一份栈，补齐操作不应该影响真正的内部状态
+	stack := append([]stackLevel{}, t.stack...)
+
+	for level := 0; level < len(stack); level++ {
+		if !stack[level].full {
+			continue
+		}
+		if level == len(stack)-1 {
+			break
+		}
+		// 往上找第一个已经 full 的层，把当前层补齐后合并上去
+		var filler []byte
+		switch t.policy {
+		case DuplicateLast:
+			filler = stack[level].hash
+		case EmptyHash:
+			filler = t.hash([]byte{})
+		case Error:
+			return nil, errOddLeafCount
+		default:
+			filler = t.hash([]byte{})
+		}
+		combined := append(append(append([]byte{}, t.pathByteAt(level)...), stack[level].hash...), filler...)
+		parent := t.hash(combined)
+		if level+1 >= len(stack) {
+			stack = append(stack, stackLevel{})
+		}
+		if stack[level+1].full {
+			combined2 := append(append(append([]byte{}, t.pathByteAt(level+1)...), stack[level+1].hash...), parent...)
+			stack[level+1] = stackLevel{hash: t.hash(combined2), full: true}
+		} else {
+			stack[level+1] = stackLevel{hash: parent, full: true}
+		}
+	}
+
+	for i := len(stack) - 1; i >= 0; i-- {
+		if stack[i].full {
+			return stack[i].hash, nil
+		}
+	}
+	return nil, errors.New("stacktree: inconsistent internal state")
+}
+
+// Checkpoint 快照当前的栈状态，供 prover 在追加更多叶子之前先对这一段生成见证
+func (t *StackTree) Checkpoint() *stackTreeSnapshot {
+	return &stackTreeSnapshot{
+		stack:        append([]stackLevel{}, t.stack...),
+		stackBase:    append([]int{}, t.stackBase...),
+		leaves:       t.leaves,
+		lastLeaf:     t.lastLeaf,
+		leafSiblings: copyLeafSiblings(t.leafSiblings),
+	}
+}
+
+// Restore 把树还原到某次 Checkpoint 时的状态，继续从那里追加叶子
+func (t *StackTree) Restore(snap *stackTreeSnapshot) {
+	t.stack = append([]stackLevel{}, snap.stack...)
+	t.stackBase = append([]int{}, snap.stackBase...)
+	t.leaves = snap.leaves
+	t.lastLeaf = snap.lastLeaf
+	t.leafSiblings = copyLeafSiblings(snap.leafSiblings)
+}
+
+func copyLeafSiblings(src map[int][][]byte) map[int][][]byte {
+	dst := make(map[int][][]byte, len(src))
+	for idx, sibs := range src {
+		dst[idx] = append([][]byte{}, sibs...)
+	}
+	return dst
+}
+
+// IsProofStable 报告当前叶子数量是否为 2 的幂。只有在这种状态下，insert 才会把每一层都配对、
+// 合并干净，不留任何悬空层——Root() 不需要做 OddLeafPolicy 补齐，ProofFor 重放出的 leafSiblings
+// 就是通向 Root() 返回值的完整路径
+func (t *StackTree) IsProofStable() bool {
+	return t.leaves > 0 && t.leaves&(t.leaves-1) == 0
+}
+
+// ProofFor 为已经写入的第 index 个叶子（从 0 开始）构造证明
+// 返回的是 insert 在真正发生合并那一刻，按这个叶子下标记录进 leafSiblings 的兄弟哈希原样重放，
+// 而不是读取当前（很可能已经被后续合并清空）的 t.stack——那样拿到的兄弟跟这个叶子毫无关系
+// 返回值与 MerkleTree.GetProof 保持同样的约定：siblings 自底向上，helper 标记左右，leafNum 标记每层可达叶子数。
+// 叶子数量不是 2 的幂时，Root() 要靠 OddLeafPolicy 临时补齐栈里剩下的悬空层才能算出根，而那次补齐
+// 从不写回 leafSiblings（见 Root 的注释），这里重放出来的路径会在补齐发生的那一层后断掉、对不上
+// Root() 真正的返回值，所以直接拒绝，调用方应该要么只在叶子数凑够 2 的幂时取证明，要么自己先用
+// EmptyHash/DuplicateLast 之类的占位叶子把树补成 2 的幂
+func (t *StackTree) ProofFor(index int) (siblings [][]byte, helper []int, leafNum []int, err error) {
+	if !t.IsProofStable() {
+		return nil, nil, nil, errNotProofStable
+	}
+
+	recorded := t.leafSiblings[index]
+	pos := index
+	for level := 0; level < len(recorded); level++ {
+		helper = append(helper, boolToInt(pos%2 == 1))
+		leafNum = append(leafNum, 1<<uint(level))
+		siblings = append(siblings, recorded[level])
+		pos /= 2
+	}
+	return siblings, helper, leafNum, nil
+}
+
+func boolToInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"testing"
+)
+
+// sha256Hash 是测试专用的哈希函数，StackTree 对哈希原语没有要求，用 sha256 避免 MiMC 的分块对齐问题，
+// 专注验证 Push/Root/ProofFor 之间的结构一致性
+func sha256Hash(data []byte) []byte {
+	sum := sha256.Sum256(data)
+	return sum[:]
+}
+
+// recomputeStackRoot 独立于 StackTree 重放 ProofFor 给出的 siblings/helper，用来验证它们确实能
+// 折算出 Root() 返回的同一个根，而不是信任树自己的记录
+func recomputeStackRoot(hash func([]byte) []byte, leaf []byte, siblings [][]byte, helper []int) []byte {
+	cur := hash(leaf)
+	for i, sibling := range siblings {
+		if helper[i] == 0 {
+			cur = hash(append(append([]byte{}, cur...), sibling...))
+		} else {
+			cur = hash(append(append([]byte{}, sibling...), cur...))
+		}
+	}
+	return cur
+}
+
+func TestStackTreeProofForPowerOfTwoLeavesRecomputesToRoot(t *testing.T) {
+	tree := NewStackTree(sha256Hash, nil)
+	var leaves [][]byte
+	for i := 0; i < 8; i++ {
+		leaf := []byte(fmt.Sprintf("leaf-%d", i))
+		leaves = append(leaves, leaf)
+		if err := tree.Push(leaf); err != nil {
+			t.Fatalf("push %d: %v", i, err)
+		}
+	}
+
+	if !tree.IsProofStable() {
+		t.Fatal("8 leaves should be proof-stable")
+	}
+
+	root, err := tree.Root()
+	if err != nil {
+		t.Fatalf("root: %v", err)
+	}
+
+	for i, leaf := range leaves {
+		siblings, helper, _, err := tree.ProofFor(i)
+		if err != nil {
+			t.Fatalf("leaf %d: ProofFor returned unexpected error: %v", i, err)
+		}
+		if got := recomputeStackRoot(sha256Hash, leaf, siblings, helper); !bytes.Equal(got, root) {
+			t.Fatalf("leaf %d: proof does not recompute to the tree root", i)
+		}
+	}
+}
+
+func TestStackTreeProofForRejectsNonPowerOfTwoLeafCounts(t *testing.T) {
+	for _, n := range []int{3, 5, 7, 17} {
+		tree := NewStackTree(sha256Hash, nil)
+		for i := 0; i < n; i++ {
+			if err := tree.Push([]byte(fmt.Sprintf("leaf-%d", i))); err != nil {
+				t.Fatalf("n=%d: push %d: %v", n, i, err)
+			}
+		}
+
+		if tree.IsProofStable() {
+			t.Fatalf("n=%d: tree should not be proof-stable", n)
+		}
+
+		// Root() 仍然能算出一个值（靠 OddLeafPolicy 临时补齐），但 ProofFor 不能假装能重放出通向它的路径
+		if _, err := tree.Root(); err != nil {
+			t.Fatalf("n=%d: root: %v", n, err)
+		}
+		if _, _, _, err := tree.ProofFor(0); err != errNotProofStable {
+			t.Fatalf("n=%d: expected errNotProofStable, got %v", n, err)
+		}
+	}
+}
+
+func TestStackTreeCheckpointRestoreKeepsProofsConsistent(t *testing.T) {
+	tree := NewStackTree(sha256Hash, nil)
+	for i := 0; i < 4; i++ {
+		if err := tree.Push([]byte(fmt.Sprintf("leaf-%d", i))); err != nil {
+			t.Fatalf("push %d: %v", i, err)
+		}
+	}
+	snap := tree.Checkpoint()
+	rootAt4, err := tree.Root()
+	if err != nil {
+		t.Fatalf("root at 4: %v", err)
+	}
+
+	for i := 4; i < 8; i++ {
+		if err := tree.Push([]byte(fmt.Sprintf("leaf-%d", i))); err != nil {
+			t.Fatalf("push %d: %v", i, err)
+		}
+	}
+
+	tree.Restore(snap)
+	if !tree.IsProofStable() {
+		t.Fatal("restored 4-leaf tree should be proof-stable")
+	}
+	restoredRoot, err := tree.Root()
+	if err != nil {
+		t.Fatalf("root after restore: %v", err)
+	}
+	if !bytes.Equal(restoredRoot, rootAt4) {
+		t.Fatal("Restore did not bring the tree back to its checkpointed root")
+	}
+
+	siblings, helper, _, err := tree.ProofFor(0)
+	if err != nil {
+		t.Fatalf("ProofFor after restore: %v", err)
+	}
+	if got := recomputeStackRoot(sha256Hash, []byte("leaf-0"), siblings, helper); !bytes.Equal(got, restoredRoot) {
+		t.Fatal("proof after restore does not recompute to the restored root")
+	}
+}
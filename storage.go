@@ -0,0 +1,92 @@
+package main
+
+import (
+	"encoding/hex"
+	"errors"
+	"sync"
+)
+
+// ErrKeyNotFound 在 Get 查不到对应节点时返回，调用方用来区分"确实为空"还是底层出错
+var ErrKeyNotFound = errors.New("storage: key not found")
+
+// Storage 是节点按哈希寻址的 KV 存取接口，MerkleTree 过去把所有层都摊在内存里的 TreeLayers 不适合超出内存的树，
+// 换成这个接口后节点可以落到任意后端：内存、BoltDB、Badger，都实现同一套 Get/Put/NewTx/Commit
+type Storage interface {
+	Get(key []byte) ([]byte, error)
+	Put(key, value []byte) error
+	NewTx() (StorageTx, error)
+}
+
+// StorageTx 是一次性的读写事务：在内部攒一批 Put，Commit 时整体落盘，Discard 时整体丢弃
+type StorageTx interface {
+	Storage
+	Commit() error
+	Discard()
+}
+
+// MemStorage 是最简单的实现，供测试和小规模场景使用，内部用 RWMutex 保护一个 map
+type MemStorage struct {
+	mu   sync.RWMutex
+	data map[string][]byte
+}
+
+// NewMemStorage 构造一个空的内存存储
+func NewMemStorage() *MemStorage {
+	return &MemStorage{data: make(map[string][]byte)}
+}
+
+func (s *MemStorage) Get(key []byte) ([]byte, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	v, ok := s.data[hex.EncodeToString(key)]
+	if !ok {
+		return nil, ErrKeyNotFound
+	}
+	return v, nil
+}
+
+func (s *MemStorage) Put(key, value []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data[hex.EncodeToString(key)] = value
+	return nil
+}
+
+// NewTx 对 MemStorage 而言事务就是一个攒了若干 Put 的缓冲区，Commit 时整体写回底层 map
+func (s *MemStorage) NewTx() (StorageTx, error) {
+	return &memTx{parent: s, pending: make(map[string][]byte)}, nil
+}
+
+type memTx struct {
+	parent  *MemStorage
+	pending map[string][]byte
+}
+
+func (tx *memTx) Get(key []byte) ([]byte, error) {
+	if v, ok := tx.pending[hex.EncodeToString(key)]; ok {
+		return v, nil
+	}
+	return tx.parent.Get(key)
+}
+
+func (tx *memTx) Put(key, value []byte) error {
+	tx.pending[hex.EncodeToString(key)] = value
+	return nil
+}
+
+func (tx *memTx) NewTx() (StorageTx, error) {
+	return nil, errors.New("storage: nested transactions are not supported")
+}
+
+func (tx *memTx) Commit() error {
+	tx.parent.mu.Lock()
+	defer tx.parent.mu.Unlock()
+	for k, v := range tx.pending {
+		tx.parent.data[k] = v
+	}
+	return nil
+}
+
+func (tx *memTx) Discard() {
+	tx.pending = nil
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"errors"
+
+	badger "github.com/dgraph-io/badger/v4"
+)
+
+var errNestedBadgerTx = errors.New("storage: nested transactions are not supported")
+
+// BadgerStorage 把节点落到 Badger（LSM 树）里，比 BoltDB 更适合写多读少、节点数特别大的场景
+type BadgerStorage struct {
+	db *badger.DB
+}
+
+// NewBadgerStorage 用给定目录打开（或创建）一个 Badger 数据库
+func NewBadgerStorage(dir string) (*BadgerStorage, error) {
+	db, err := badger.Open(badger.DefaultOptions(dir))
+	if err != nil {
+		return nil, err
+	}
+	return &BadgerStorage{db: db}, nil
+}
+
+func (s *BadgerStorage) Get(key []byte) ([]byte, error) {
+	var value []byte
+	err := s.db.View(func(tx *badger.Txn) error {
+		item, err := tx.Get(key)
+		if err == badger.ErrKeyNotFound {
+			return ErrKeyNotFound
+		}
+		if err != nil {
+			return err
+		}
+		return item.Value(func(v []byte) error {
+			value = append([]byte{}, v...)
+			return nil
+		})
+	})
+	return value, err
+}
+
+func (s *BadgerStorage) Put(key, value []byte) error {
+	return s.db.Update(func(tx *badger.Txn) error {
+		return tx.Set(key, value)
+	})
+}
+
+// NewTx 包一层 Badger 的读写事务
+func (s *BadgerStorage) NewTx() (StorageTx, error) {
+	return &badgerTx{txn: s.db.NewTransaction(true)}, nil
+}
+
+type badgerTx struct {
+	txn *badger.Txn
+}
+
+func (t *badgerTx) Get(key []byte) ([]byte, error) {
+	item, err := t.txn.Get(key)
+	if err == badger.ErrKeyNotFound {
+		return nil, ErrKeyNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	var value []byte
+	err = item.Value(func(v []byte) error {
+		value = append([]byte{}, v...)
+		return nil
+	})
+	return value, err
+}
+
+func (t *badgerTx) Put(key, value []byte) error {
+	return t.txn.Set(key, value)
+}
+
+func (t *badgerTx) NewTx() (StorageTx, error) {
+	return nil, errNestedBadgerTx
+}
+
+func (t *badgerTx) Commit() error {
+	return t.txn.Commit()
+}
+
+func (t *badgerTx) Discard() {
+	t.txn.Discard()
+}
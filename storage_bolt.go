@@ -0,0 +1,91 @@
+package main
+
+import (
+	"errors"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// merkleBucket 是 BoltDB 里存放 Merkle 节点的唯一桶名
+var merkleBucket = []byte("merkle_nodes")
+
+var errNestedBoltTx = errors.New("storage: nested transactions are not supported")
+
+// BoltStorage 把节点落到一个 BoltDB 文件里，适合单机场景下需要比内存大很多的树
+type BoltStorage struct {
+	db *bolt.DB
+}
+
+// NewBoltStorage 打开（或创建）指定路径的 BoltDB 文件，并确保节点桶存在
+func NewBoltStorage(path string) (*BoltStorage, error) {
+	db, err := bolt.Open(path, 0600, nil)
+	if err != nil {
+		return nil, err
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(merkleBucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &BoltStorage{db: db}, nil
+}
+
+func (s *BoltStorage) Get(key []byte) ([]byte, error) {
+	var value []byte
+	err := s.db.View(func(tx *bolt.Tx) error {
+		v := tx.Bucket(merkleBucket).Get(key)
+		if v == nil {
+			return ErrKeyNotFound
+		}
+		value = append([]byte{}, v...)
+		return nil
+	})
+	return value, err
+}
+
+func (s *BoltStorage) Put(key, value []byte) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(merkleBucket).Put(key, value)
+	})
+}
+
+// NewTx 包一层 BoltDB 的读写事务，Put 立刻写进事务里，Commit/Discard 对应事务的提交或回滚
+func (s *BoltStorage) NewTx() (StorageTx, error) {
+	tx, err := s.db.Begin(true)
+	if err != nil {
+		return nil, err
+	}
+	return &boltTx{tx: tx, bucket: tx.Bucket(merkleBucket)}, nil
+}
+
+type boltTx struct {
+	tx     *bolt.Tx
+	bucket *bolt.Bucket
+}
+
+func (t *boltTx) Get(key []byte) ([]byte, error) {
+	v := t.bucket.Get(key)
+	if v == nil {
+		return nil, ErrKeyNotFound
+	}
+	return append([]byte{}, v...), nil
+}
+
+func (t *boltTx) Put(key, value []byte) error {
+	return t.bucket.Put(key, value)
+}
+
+func (t *boltTx) NewTx() (StorageTx, error) {
+	return nil, errNestedBoltTx
+}
+
+func (t *boltTx) Commit() error {
+	return t.tx.Commit()
+}
+
+func (t *boltTx) Discard() {
+	t.tx.Rollback()
+}